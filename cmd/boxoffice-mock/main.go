@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"interview/internal/boxoffice/boxofficetest"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// 本地假票房上游：按mock-boxoffice.json提供 GET /boxoffice?title=（开发/测试用）
+func main() {
+	_ = godotenv.Load()
+
+	addr := flag.String("addr", "127.0.0.1:8090", "listen address")
+	data := flag.String("data", "", "records file in mock-boxoffice.json format (default: embedded copy)")
+	apiKey := flag.String("api-key", os.Getenv("BOXOFFICE_API_KEY"), "required X-API-Key (empty disables the check)")
+	latency := flag.Duration("latency", 0, "fixed latency added to every request")
+	jitter := flag.Duration("jitter", 0, "random extra latency in [0, jitter)")
+	faultRate := flag.Float64("fault-rate", 0, "probability (0-1) of answering 500")
+	seed := flag.Int64("seed", 0, "random seed for jitter and faults (0 = time based)")
+	flag.Parse()
+
+	if *faultRate < 0 || *faultRate > 1 {
+		log.Fatal("-fault-rate must be between 0 and 1")
+	}
+
+	records, err := boxofficetest.LoadRecords(*data)
+	if err != nil {
+		log.Fatalf("failed to load records: %v", err)
+	}
+
+	h := boxofficetest.NewHandler(records, boxofficetest.Options{
+		APIKey:    *apiKey,
+		Latency:   *latency,
+		Jitter:    *jitter,
+		FaultRate: *faultRate,
+		Seed:      *seed,
+	})
+
+	srv := &http.Server{Addr: *addr, Handler: h, ReadHeaderTimeout: 5 * time.Second}
+	log.Printf("box office mock listening on %s (%d movies)", *addr, len(records))
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("failed to start box office mock: %v", err)
+	}
+}
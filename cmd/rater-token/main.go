@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"interview/internal/auth"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// 用RATER_TOKEN_SECRET给rater签发X-Rater-Token（开发/测试用）
+func main() {
+	raterID := flag.String("id", "", "rater id to sign")
+	ttl := flag.Duration("ttl", 24*time.Hour, "token lifetime")
+	flag.Parse()
+
+	_ = godotenv.Load()
+	secret := os.Getenv("RATER_TOKEN_SECRET")
+	if secret == "" {
+		log.Fatal("missing required env var: RATER_TOKEN_SECRET")
+	}
+	if *raterID == "" {
+		log.Fatal("-id is required")
+	}
+
+	fmt.Println(auth.SignRaterToken([]byte(secret), *raterID, time.Now().Add(*ttl)))
+}
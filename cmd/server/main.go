@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"interview/internal/config"
 	"interview/internal/db"
@@ -8,6 +9,7 @@ import (
 	"interview/internal/server/handlers"
 	"interview/internal/server/middleware"
 	"log"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -26,7 +28,25 @@ func main() {
 
 	// 初始化handler和middleware
 	h := handlers.NewHandlerSet(conn, cfg)
-	m := middleware.NewMiddlewareSet(cfg)
+	m := middleware.NewMiddlewareSet(conn, cfg)
+
+	// 异步票房补全的worker
+	if h.Enrichment != nil {
+		h.Enrichment.Start(context.Background())
+	}
+
+	// 提交评分后的刷分检测
+	h.Anomaly.Start(context.Background())
+
+	// 清理过期的票房缓存（BOXOFFICE_CACHE=postgres）
+	if h.CachePurger != nil {
+		h.CachePurger.StartPurge(context.Background(), max(cfg.BoxofficeCacheTTL, time.Minute))
+	}
+
+	// 定时刷新过期的票房数据
+	if cfg.RefreshEnabled {
+		h.Refresher.Start(context.Background())
+	}
 
 	// 生成Gin router
 	r := server.NewRouter(server.RouterDeps{
@@ -0,0 +1,8 @@
+package interview
+
+import _ "embed"
+
+// ExchangeRates exchange-rates.csv的内容，没有配置EXCHANGE_RATES_FILE时用这份内置汇率表
+//
+//go:embed exchange-rates.csv
+var ExchangeRates []byte
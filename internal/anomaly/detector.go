@@ -0,0 +1,287 @@
+package anomaly
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"interview/internal/config"
+)
+
+// 极端评分阈值：刷低分看 <= lowRating，刷高分看 >= highRating
+const (
+	lowRating  = 1.0
+	highRating = 4.5
+)
+
+// 后台检测：待检测电影的队列长度和单次检测的超时
+const (
+	queueSize     = 1024
+	detectTimeout = 10 * time.Second
+)
+
+// Detector 按电影检测评分异常（短时间内评分量激增且分布明显偏移）
+type Detector struct {
+	DB *sqlx.DB
+
+	Enabled bool
+	// Window 检测窗口（最近一段时间）
+	Window time.Duration
+	// BaselineWindow 窗口之前用作基线的时间段
+	BaselineWindow time.Duration
+	// MinVolume 窗口内最少评分数，少于此值不认为是激增
+	MinVolume int
+	// VolumeFactor 窗口评分数相对基线（按窗口长度折算）的倍数
+	VolumeFactor float64
+	// ShiftThreshold 窗口平均分相对基线平均分的偏移阈值
+	ShiftThreshold float64
+	// ExtremeShare 没有基线时，窗口内极端评分占比阈值
+	ExtremeShare float64
+	// FreshRaterAge 首次评分时间在此之内的rater视为新账号
+	FreshRaterAge time.Duration
+	// ExcludeSuspicious GetRating聚合时是否排除可疑评分
+	ExcludeSuspicious bool
+
+	// 提交评分后待检测的电影，由Start启动的goroutine处理；pending里的电影已在队列中，不重复入队
+	queue   chan string
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// Report 单部电影的检测结果
+type Report struct {
+	MovieTitle        string   `json:"movieTitle"`
+	RecentCount       int      `json:"recentCount"`
+	RecentAverage     float64  `json:"recentAverage"`
+	BaselineCount     int      `json:"baselineCount"`
+	BaselineRate      float64  `json:"baselineRatePerWindow"`
+	BaselineAverage   *float64 `json:"baselineAverage"`
+	VolumeSpike       bool     `json:"volumeSpike"`
+	DistributionShift bool     `json:"distributionShift"`
+	Direction         string   `json:"direction,omitempty"` // "down" 刷低分 / "up" 刷高分
+	Flagged           int      `json:"flagged"`
+	SuspiciousTotal   int      `json:"suspiciousTotal"`
+}
+
+// Anomalous 是否同时满足量激增和分布偏移
+func (r *Report) Anomalous() bool {
+	return r.VolumeSpike && r.DistributionShift
+}
+
+// 创建检测器
+func New(db *sqlx.DB, cfg config.Config) *Detector {
+	return &Detector{
+		DB:                db,
+		Enabled:           cfg.AnomalyEnabled,
+		Window:            cfg.AnomalyWindow,
+		BaselineWindow:    cfg.AnomalyBaselineWindow,
+		MinVolume:         cfg.AnomalyMinVolume,
+		VolumeFactor:      cfg.AnomalyVolumeFactor,
+		ShiftThreshold:    cfg.AnomalyShiftThreshold,
+		ExtremeShare:      cfg.AnomalyExtremeShare,
+		FreshRaterAge:     cfg.AnomalyFreshRaterAge,
+		ExcludeSuspicious: cfg.RatingExcludeSuspicious,
+		queue:             make(chan string, queueSize),
+		pending:           map[string]bool{},
+	}
+}
+
+// Schedule 把电影加入后台检测队列，不阻塞提交评分的请求；已在队列中的电影不重复加入，队列满时丢弃
+func (d *Detector) Schedule(title string) {
+	if !d.Enabled {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending[title] {
+		return
+	}
+	select {
+	case d.queue <- title:
+		d.pending[title] = true
+	default:
+		log.Printf("anomaly: queue full, skipping detection for %q", title)
+	}
+}
+
+// Start 启动后台检测的goroutine，处理Schedule加入的电影
+func (d *Detector) Start(ctx context.Context) {
+	if !d.Enabled {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case title := <-d.queue:
+				// 先移出pending，检测期间的新评分会让电影再入队一次
+				d.mu.Lock()
+				delete(d.pending, title)
+				d.mu.Unlock()
+
+				detectCtx, cancel := context.WithTimeout(ctx, detectTimeout)
+				if _, err := d.Detect(detectCtx, title); err != nil {
+					log.Printf("anomaly: detection failed for %q: %v", title, err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// Analyze 只读分析，不修改评分
+func (d *Detector) Analyze(ctx context.Context, title string) (*Report, error) {
+	var (
+		recentCount, baselineCount, lowCount, highCount, suspiciousTotal int
+		recentAvg, baselineAvg                                           sql.NullFloat64
+	)
+
+	// 时间都在数据库侧计算，避免应用与数据库时区不一致
+	err := d.DB.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE updated_at >= NOW() - $2::float8 * INTERVAL '1 second'),
+			AVG(rating) FILTER (WHERE updated_at >= NOW() - $2::float8 * INTERVAL '1 second'),
+			COUNT(*) FILTER (WHERE updated_at >= NOW() - $2::float8 * INTERVAL '1 second' AND rating <= $4),
+			COUNT(*) FILTER (WHERE updated_at >= NOW() - $2::float8 * INTERVAL '1 second' AND rating >= $5),
+			COUNT(*) FILTER (WHERE NOT suspicious AND updated_at < NOW() - $2::float8 * INTERVAL '1 second'
+				AND updated_at >= NOW() - ($2::float8 + $3::float8) * INTERVAL '1 second'),
+			AVG(rating) FILTER (WHERE NOT suspicious AND updated_at < NOW() - $2::float8 * INTERVAL '1 second'
+				AND updated_at >= NOW() - ($2::float8 + $3::float8) * INTERVAL '1 second'),
+			COUNT(*) FILTER (WHERE suspicious)
+		FROM ratings
+		WHERE movie_title = $1
+	`, title, d.Window.Seconds(), d.BaselineWindow.Seconds(), lowRating, highRating).Scan(
+		&recentCount, &recentAvg, &lowCount, &highCount, &baselineCount, &baselineAvg, &suspiciousTotal)
+	if err != nil {
+		return nil, fmt.Errorf("anomaly analyze: %w", err)
+	}
+
+	r := &Report{
+		MovieTitle:      title,
+		RecentCount:     recentCount,
+		BaselineCount:   baselineCount,
+		SuspiciousTotal: suspiciousTotal,
+	}
+	d.evaluate(r, lowCount, highCount, recentAvg, baselineAvg)
+	return r, nil
+}
+
+// evaluate 根据窗口和基线的统计判断量激增和分布偏移，r里已经填好了RecentCount和BaselineCount
+func (d *Detector) evaluate(r *Report, lowCount, highCount int, recentAvg, baselineAvg sql.NullFloat64) {
+	recentCount := r.RecentCount
+	if recentAvg.Valid {
+		r.RecentAverage = math.Round(recentAvg.Float64*10) / 10
+	}
+
+	// 基线按窗口长度折算成“每个窗口的评分数”，至少按1算，避免冷门电影被少量评分触发
+	windows := d.BaselineWindow.Seconds() / d.Window.Seconds()
+	if windows > 0 {
+		r.BaselineRate = float64(r.BaselineCount) / windows
+	}
+	r.VolumeSpike = recentCount >= d.MinVolume &&
+		float64(recentCount) >= d.VolumeFactor*math.Max(r.BaselineRate, 1)
+
+	if recentCount == 0 {
+		return
+	}
+
+	if baselineAvg.Valid {
+		avg := math.Round(baselineAvg.Float64*10) / 10
+		r.BaselineAverage = &avg
+		shift := recentAvg.Float64 - baselineAvg.Float64
+		if math.Abs(shift) >= d.ShiftThreshold {
+			r.DistributionShift = true
+			r.Direction = "up"
+			if shift < 0 {
+				r.Direction = "down"
+			}
+		}
+	} else {
+		// 没有基线（新电影）时看极端评分占比
+		lowShare := float64(lowCount) / float64(recentCount)
+		highShare := float64(highCount) / float64(recentCount)
+		if lowShare >= d.ExtremeShare {
+			r.DistributionShift = true
+			r.Direction = "down"
+		} else if highShare >= d.ExtremeShare {
+			r.DistributionShift = true
+			r.Direction = "up"
+		}
+	}
+}
+
+// Detect 分析并把窗口内新账号的极端评分标记为可疑
+func (d *Detector) Detect(ctx context.Context, title string) (*Report, error) {
+	if !d.Enabled {
+		return nil, nil
+	}
+
+	r, err := d.Analyze(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Anomalous() {
+		return r, nil
+	}
+
+	ratingCond := "rating <= $4"
+	threshold := lowRating
+	if r.Direction == "up" {
+		ratingCond = "rating >= $4"
+		threshold = highRating
+	}
+	reason := fmt.Sprintf("spike of %d ratings in %s (baseline %.1f per window), shift %s",
+		r.RecentCount, d.Window, r.BaselineRate, r.Direction)
+
+	res, err := d.DB.ExecContext(ctx, `
+		UPDATE ratings r
+		SET suspicious = TRUE, suspicious_reason = $5, flagged_at = NOW()
+		WHERE r.movie_title = $1
+		  AND NOT r.suspicious
+		  AND r.updated_at >= NOW() - $2::float8 * INTERVAL '1 second'
+		  AND `+ratingCond+`
+		  AND NOT EXISTS (
+			SELECT 1 FROM ratings o
+			WHERE o.rater_id = r.rater_id
+			  AND o.created_at < NOW() - $3::float8 * INTERVAL '1 second'
+		  )
+	`, title, d.Window.Seconds(), d.FreshRaterAge.Seconds(), threshold, reason)
+	if err != nil {
+		return r, fmt.Errorf("anomaly flag: %w", err)
+	}
+
+	n, _ := res.RowsAffected()
+	r.Flagged = int(n)
+	r.SuspiciousTotal += int(n)
+	return r, nil
+}
+
+// Reports 对窗口内有评分或已有可疑评分的电影生成报告
+func (d *Detector) Reports(ctx context.Context) ([]Report, error) {
+	var titles []string
+	err := d.DB.SelectContext(ctx, &titles, `
+		SELECT DISTINCT movie_title
+		FROM ratings
+		WHERE suspicious OR updated_at >= NOW() - $1::float8 * INTERVAL '1 second'
+		ORDER BY movie_title
+	`, d.Window.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("anomaly reports: %w", err)
+	}
+
+	reports := []Report{}
+	for _, t := range titles {
+		r, err := d.Analyze(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *r)
+	}
+	return reports, nil
+}
@@ -0,0 +1,76 @@
+package anomaly
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	d := &Detector{
+		Window:         time.Hour,
+		BaselineWindow: 24 * time.Hour,
+		MinVolume:      10,
+		VolumeFactor:   5,
+		ShiftThreshold: 1.5,
+		ExtremeShare:   0.6,
+	}
+	avg := func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+
+	tests := []struct {
+		name                   string
+		recent, baseline       int
+		low, high              int
+		recentAvg, baselineAvg sql.NullFloat64
+		wantSpike, wantShift   bool
+		wantDirection          string
+	}{
+		{"no ratings", 0, 0, 0, 0, sql.NullFloat64{}, sql.NullFloat64{}, false, false, ""},
+		{"spike and shift down", 30, 48, 25, 0, avg(1.2), avg(4.1), true, true, "down"},
+		{"spike and shift up", 30, 48, 0, 25, avg(4.9), avg(2.5), true, true, "up"},
+		// 少于MinVolume条时不算激增，分布偏移照样判断
+		{"below min volume", 9, 0, 9, 0, avg(0.5), sql.NullFloat64{}, false, true, "down"},
+		// 基线每个窗口10条，30条没有达到5倍
+		{"busy baseline is not a spike", 30, 240, 0, 0, avg(3.0), avg(3.1), false, false, ""},
+		{"small shift", 30, 48, 0, 0, avg(3.0), avg(4.0), true, false, ""},
+		// 没有基线时看极端评分占比
+		{"new movie mostly low", 20, 0, 15, 0, avg(1.0), sql.NullFloat64{}, true, true, "down"},
+		{"new movie mostly high", 20, 0, 0, 15, avg(4.8), sql.NullFloat64{}, true, true, "up"},
+		{"new movie mixed", 20, 0, 5, 5, avg(3.0), sql.NullFloat64{}, true, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Report{RecentCount: tt.recent, BaselineCount: tt.baseline}
+			d.evaluate(r, tt.low, tt.high, tt.recentAvg, tt.baselineAvg)
+
+			if r.VolumeSpike != tt.wantSpike || r.DistributionShift != tt.wantShift || r.Direction != tt.wantDirection {
+				t.Errorf("evaluate() = spike %v, shift %v, direction %q; want %v, %v, %q",
+					r.VolumeSpike, r.DistributionShift, r.Direction, tt.wantSpike, tt.wantShift, tt.wantDirection)
+			}
+			if r.Anomalous() != (tt.wantSpike && tt.wantShift) {
+				t.Errorf("Anomalous() = %v, want %v", r.Anomalous(), tt.wantSpike && tt.wantShift)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	d := &Detector{Enabled: true, queue: make(chan string, 2), pending: map[string]bool{}}
+
+	// 已在队列中的电影不重复入队，队列满时丢弃
+	for _, title := range []string{"Inception", "Inception", "Avatar", "Titanic"} {
+		d.Schedule(title)
+	}
+	if got := len(d.queue); got != 2 {
+		t.Fatalf("queue length = %d, want 2", got)
+	}
+	if d.pending["Titanic"] {
+		t.Errorf("dropped title is marked pending")
+	}
+
+	disabled := &Detector{queue: make(chan string, 2), pending: map[string]bool{}}
+	disabled.Schedule("Inception")
+	if got := len(disabled.queue); got != 0 {
+		t.Errorf("disabled detector queue length = %d, want 0", got)
+	}
+}
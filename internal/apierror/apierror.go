@@ -0,0 +1,166 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code 对外暴露的错误码
+type Code string
+
+const (
+	CodeBadRequest      Code = "BAD_REQUEST"
+	CodeUnauthorized    Code = "UNAUTHORIZED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeTooManyRequests Code = "TOO_MANY_REQUESTS"
+	CodeInternal        Code = "INTERNAL_ERROR"
+	CodeUpstream        Code = "UPSTREAM_ERROR"
+)
+
+// statusByCode 每个错误码对应唯一的HTTP状态码
+var statusByCode = map[Code]int{
+	CodeBadRequest:      http.StatusBadRequest,
+	CodeUnauthorized:    http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeTooManyRequests: http.StatusTooManyRequests,
+	CodeInternal:        http.StatusInternalServerError,
+	CodeUpstream:        http.StatusBadGateway,
+}
+
+// Status 错误码对应的HTTP状态码，未知错误码按500处理
+func (c Code) Status() int {
+	if s, ok := statusByCode[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// 常用的错误，message与openapi.yml中的示例一致
+var (
+	ErrUnauthorized    = New(CodeUnauthorized, "Missing or invalid authentication information")
+	ErrForbidden       = New(CodeForbidden, "No permission to perform this operation")
+	ErrInvalidBody     = New(CodeBadRequest, "Invalid request body")
+	ErrTooManyRequests = New(CodeTooManyRequests, "Rate limit exceeded, please retry later")
+	ErrInternal        = New(CodeInternal, "Internal server error")
+)
+
+// FieldError 字段级错误，Field为JSON Pointer（如 /releaseDate）
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error API错误
+type Error struct {
+	Code    Code
+	Message string
+	Details []FieldError
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf 带格式化message的错误
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// NotFound 资源不存在
+func NotFound(what string) *Error {
+	return New(CodeNotFound, what+" not found")
+}
+
+// Internal 内部错误，message只描述失败的操作，不暴露底层错误
+func Internal(message string) *Error {
+	return New(CodeInternal, message)
+}
+
+// Validation 带字段级详情的校验错误
+func Validation(details ...FieldError) *Error {
+	return &Error{Code: CodeBadRequest, Message: "Request validation failed", Details: details}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// Status HTTP状态码
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// WithDetails 返回附带字段错误的副本
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	cp := *e
+	cp.Details = append(append([]FieldError{}, e.Details...), details...)
+	return &cp
+}
+
+// body 默认的 {code, message, details} 结构（openapi.yml中的Error）
+type body struct {
+	Code    Code         `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// problem RFC 7807 application/problem+json，code和errors作为扩展字段
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+const problemContentType = "application/problem+json"
+
+// Write 写错误响应；非*Error按内部错误处理。Accept包含application/problem+json时返回RFC 7807格式
+func Write(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = ErrInternal
+	}
+
+	status := e.Status()
+	if wantsProblem(c) {
+		b, _ := json.Marshal(problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   e.Message,
+			Instance: c.Request.URL.Path,
+			Code:     e.Code,
+			Errors:   e.Details,
+		})
+		c.Data(status, problemContentType, b)
+		return
+	}
+
+	c.JSON(status, body{Code: e.Code, Message: e.Message, Details: e.Details})
+}
+
+// Abort 写错误响应并终止后续handler（用于中间件）
+func Abort(c *gin.Context, err error) {
+	Write(c, err)
+	c.Abort()
+}
+
+func wantsProblem(c *gin.Context) bool {
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if strings.EqualFold(mediaType, problemContentType) {
+			return true
+		}
+	}
+	return false
+}
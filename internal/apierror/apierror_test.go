@@ -0,0 +1,136 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		err             error
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "error body",
+			err:             NotFound("Movie"),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"NOT_FOUND","message":"Movie not found"}`,
+		},
+		{
+			name:            "validation details",
+			err:             Validation(FieldError{Field: "/budget", Message: "must be at least 0"}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"BAD_REQUEST","message":"Request validation failed","details":[{"field":"/budget","message":"must be at least 0"}]}`,
+		},
+		{
+			// 其他错误不能把内部信息带给调用方
+			name:            "non api error",
+			err:             errors.New("pq: connection refused"),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"INTERNAL_ERROR","message":"Internal server error"}`,
+		},
+		{
+			name:            "wrapped api error",
+			err:             fmt.Errorf("refresh: %w", New(CodeUpstream, "Box office upstream unavailable")),
+			wantStatus:      http.StatusBadGateway,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"UPSTREAM_ERROR","message":"Box office upstream unavailable"}`,
+		},
+		{
+			name:            "problem json",
+			err:             ErrInvalidBody.WithDetails(FieldError{Field: "/title", Message: "is required"}),
+			accept:          "application/json;q=0.5, application/problem+json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/problem+json",
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body",` +
+				`"instance":"/movies","code":"BAD_REQUEST","errors":[{"field":"/title","message":"is required"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/movies", nil)
+			c.Request.Header.Set("Accept", tt.accept)
+
+			Write(c, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantContentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %s, want %s", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWithDetailsCopies(t *testing.T) {
+	e := ErrInvalidBody.WithDetails(FieldError{Field: "/title", Message: "is required"})
+	if len(ErrInvalidBody.Details) != 0 {
+		t.Errorf("WithDetails() modified the shared error: %+v", ErrInvalidBody.Details)
+	}
+	if len(e.Details) != 1 {
+		t.Errorf("WithDetails() details = %+v, want 1", e.Details)
+	}
+}
+
+func TestBindLenient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		Title  string `json:"title" binding:"required"`
+		Budget *int64 `json:"budget" binding:"omitempty,min=0"`
+		Genre  string `json:"genre"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		wantDetails string
+	}{
+		{"valid", `{"title":"Inception","budget":1}`, false, `[]`},
+		{"not an object", `[1,2]`, true, `null`},
+		{"invalid json", `{`, true, `null`},
+		// 类型错误的字段只报一次，其他字段照常校验
+		{"type error and missing field", `{"title":1,"budget":"x"}`, false,
+			`[{"field":"/title","message":"must be of type string"},{"field":"/budget","message":"must be of type int64"}]`},
+		{"binding rules", `{"budget":-1}`, false,
+			`[{"field":"/title","message":"is required"},{"field":"/budget","message":"must be at least 0"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/movies", strings.NewReader(tt.body))
+
+			var req request
+			details, err := BindLenient(c, &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindLenient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got, _ := json.Marshal(details); string(got) != tt.wantDetails {
+				t.Errorf("BindLenient() details = %s, want %s", got, tt.wantDetails)
+			}
+		})
+	}
+}
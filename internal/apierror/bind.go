@@ -0,0 +1,115 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误里用json字段名而不是Go字段名，方便拼JSON Pointer
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// BindError 把ShouldBindJSON的错误转换成带字段详情的校验错误
+func BindError(err error) *Error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		details := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			details = append(details, FieldError{Field: pointer(fe.Namespace()), Message: tagMessage(fe)})
+		}
+		return ErrInvalidBody.WithDetails(details...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return ErrInvalidBody.WithDetails(FieldError{
+			Field:   "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+			Message: "must be of type " + typeErr.Type.String(),
+		})
+	}
+
+	return ErrInvalidBody
+}
+
+// BindLenient 逐个字段解析JSON对象：类型不对的字段记成字段错误并保留零值，其余字段照常解析，再跑binding标签的校验，
+// 这样调用方还能接着做自己的字段校验，一次返回所有错误。请求体不是JSON对象时返回ErrInvalidBody
+func BindLenient(c *gin.Context, dst interface{}) ([]FieldError, error) {
+	var raw map[string]json.RawMessage
+	if c.Request.Body == nil {
+		return nil, ErrInvalidBody
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&raw); err != nil || raw == nil {
+		return nil, ErrInvalidBody
+	}
+
+	details := []FieldError{}
+	failed := map[string]bool{}
+	v := reflect.ValueOf(dst).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.SplitN(v.Type().Field(i).Tag.Get("json"), ",", 2)[0]
+		msg, ok := raw[name]
+		if name == "" || name == "-" || !ok {
+			continue
+		}
+		if err := json.Unmarshal(msg, v.Field(i).Addr().Interface()); err != nil {
+			field := "/" + name
+			message := "is invalid"
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				message = "must be of type " + typeErr.Type.String()
+			}
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+			failed[field] = true
+			details = append(details, FieldError{Field: field, Message: message})
+		}
+	}
+
+	// 类型错误的字段已经报过了，不再报它的零值没通过校验
+	var verrs validator.ValidationErrors
+	if errors.As(binding.Validator.ValidateStruct(dst), &verrs) {
+		for _, fe := range verrs {
+			if field := pointer(fe.Namespace()); !failed[field] {
+				details = append(details, FieldError{Field: field, Message: tagMessage(fe)})
+			}
+		}
+	}
+	return details, nil
+}
+
+// pointer 把 "MovieCreateRequest.title" 转成 "/title"
+func pointer(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func tagMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	}
+	return "failed " + fe.Tag() + " validation"
+}
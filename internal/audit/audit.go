@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"interview/internal/domain"
+)
+
+// Event 待写入的审计事件，Before/After为任意可JSON序列化的值（nil表示不存在）
+type Event struct {
+	Actor     string
+	TokenName string
+	Action    string
+	Method    string
+	Route     string
+	Target    string
+	Before    interface{}
+	After     interface{}
+	RequestID string
+	IP        string
+}
+
+// Filter 查询条件，零值表示不过滤
+type Filter struct {
+	Actor     string
+	TokenName string
+	Action    string
+	Target    string
+	RequestID string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+	Offset    int
+}
+
+// Logger 把审计事件写入audit_log表
+type Logger struct {
+	DB *sqlx.DB
+}
+
+func New(db *sqlx.DB) *Logger {
+	return &Logger{DB: db}
+}
+
+// Record 写入一条审计事件。db传被审计的修改所在的事务，审计和修改一起提交或回滚
+func (l *Logger) Record(ctx context.Context, db sqlx.ExecerContext, e Event) error {
+	before, err := toJSON(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := toJSON(e.After)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO audit_log (actor, token_name, action, method, route, target,
+		                       before_data, after_data, diff, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, e.Actor, nullString(e.TokenName), e.Action, e.Method, e.Route, e.Target,
+		nullJSON(before), nullJSON(after), string(diff), nullString(e.RequestID), nullString(e.IP))
+	if err != nil {
+		return fmt.Errorf("audit record: %w", err)
+	}
+	return nil
+}
+
+// Query 按条件查询审计记录，按时间倒序
+func (l *Logger) Query(ctx context.Context, f Filter) ([]domain.AuditEvent, error) {
+	query := `SELECT id, actor, token_name, action, method, route, target,
+		before_data, after_data, diff, request_id, ip, created_at
+		FROM audit_log WHERE 1=1`
+	args := []interface{}{}
+
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.TokenName != "" {
+		add("token_name = $%d", f.TokenName)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Target != "" {
+		add("target = $%d", f.Target)
+	}
+	if f.RequestID != "" {
+		add("request_id = $%d", f.RequestID)
+	}
+	if f.Since != nil {
+		add("created_at >= $%d", *f.Since)
+	}
+	if f.Until != nil {
+		add("created_at < $%d", *f.Until)
+	}
+
+	args = append(args, f.Limit, f.Offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := l.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit query: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.AuditEvent{}
+	for rows.Next() {
+		var e domain.AuditEvent
+		var tokenName, requestID, ip sql.NullString
+		var before, after, diff []byte
+		if err := rows.Scan(&e.ID, &e.Actor, &tokenName, &e.Action, &e.Method, &e.Route, &e.Target,
+			&before, &after, &diff, &requestID, &ip, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("audit scan: %w", err)
+		}
+
+		if tokenName.Valid {
+			e.TokenName = &tokenName.String
+		}
+		if requestID.Valid {
+			e.RequestID = &requestID.String
+		}
+		if ip.Valid {
+			e.IP = &ip.String
+		}
+		e.Before = before
+		e.After = after
+		if len(diff) > 0 {
+			_ = json.Unmarshal(diff, &e.Diff)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Diff 比较两个JSON对象的顶层字段，返回有变化的字段
+func Diff(before, after []byte) map[string]domain.FieldChange {
+	b := map[string]interface{}{}
+	a := map[string]interface{}{}
+	_ = json.Unmarshal(before, &b)
+	_ = json.Unmarshal(after, &a)
+
+	changes := map[string]domain.FieldChange{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(av, bv) {
+			changes[k] = domain.FieldChange{Before: bv, After: a[k]}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = domain.FieldChange{Before: nil, After: av}
+		}
+	}
+	return changes
+}
+
+func toJSON(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit marshal: %w", err)
+	}
+	return b, nil
+}
+
+// nullJSON 空值写成SQL NULL而不是JSON null；pq会把[]byte当bytea发送，所以转成string
+func nullJSON(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"unchanged", `{"title":"Inception","budget":1}`, `{"title":"Inception","budget":1}`, `{}`},
+		{"changed field", `{"title":"Inception","budget":1}`, `{"title":"Inception","budget":2}`,
+			`{"budget":{"before":1,"after":2}}`},
+		{"nested values", `{"genre":["Sci-Fi"]}`, `{"genre":["Sci-Fi","Drama"]}`,
+			`{"genre":{"before":["Sci-Fi"],"after":["Sci-Fi","Drama"]}}`},
+		// 创建和删除时另一边为空，所有字段都算变化
+		{"create", ``, `{"title":"Inception"}`, `{"title":{"before":null,"after":"Inception"}}`},
+		{"delete", `{"title":"Inception"}`, ``, `{"title":{"before":"Inception","after":null}}`},
+		{"field removed", `{"title":"Inception","budget":1}`, `{"title":"Inception"}`,
+			`{"budget":{"before":1,"after":null}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(Diff([]byte(tt.before), []byte(tt.after)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Diff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	var nilMovie *struct{ Title string }
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want []byte
+	}{
+		{"nil", nil, nil},
+		// 没有before/after时写SQL NULL，不写JSON null
+		{"typed nil pointer", nilMovie, nil},
+		{"value", struct {
+			Title string `json:"title"`
+		}{"Inception"}, []byte(`{"title":"Inception"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toJSON(tt.v)
+			if err != nil {
+				t.Fatalf("toJSON() error = %v", err)
+			}
+			if string(got) != string(tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("toJSON() = %q, want %q", got, tt.want)
+			}
+			if (nullJSON(got) == nil) != (tt.want == nil) {
+				t.Errorf("nullJSON() = %v, want NULL %v", nullJSON(got), tt.want == nil)
+			}
+		})
+	}
+}
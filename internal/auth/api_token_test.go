@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashAPIToken(t *testing.T) {
+	token, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatalf("GenerateAPIToken() error = %v", err)
+	}
+	other, _ := GenerateAPIToken()
+	if token == other {
+		t.Fatalf("GenerateAPIToken() returned the same token twice")
+	}
+	if HashAPIToken(token) != HashAPIToken(token) {
+		t.Errorf("HashAPIToken() is not deterministic")
+	}
+	if HashAPIToken(token) == HashAPIToken(other) {
+		t.Errorf("HashAPIToken() collides for different tokens")
+	}
+	if strings.Contains(HashAPIToken(token), token) {
+		t.Errorf("HashAPIToken() contains the plaintext token")
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// JWK 单个JSON Web Key（只解析用得到的字段）
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct (HMAC)
+	K string `json:"k"`
+}
+
+// key 解析后的公钥/密钥
+type key struct {
+	alg string // JWK声明的alg，可为空
+	rsa *rsa.PublicKey
+	ec  *ecdsa.PublicKey
+	oct []byte
+}
+
+// JWKS 从本地文件或URL加载的密钥集合，URL来源遇到未知kid时会重新拉取
+type JWKS struct {
+	source string
+
+	mu          sync.RWMutex
+	keys        map[string]*key
+	lastRefresh time.Time
+}
+
+// 两次重新拉取之间的最小间隔，避免伪造kid打爆JWKS服务
+const jwksMinRefresh = 5 * time.Minute
+
+// LoadJWKS source为http(s) URL或本地文件路径
+func LoadJWKS(ctx context.Context, source string) (*JWKS, error) {
+	s := &JWKS{source: source}
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewHMACKeySet 只包含一个HS256密钥的集合（没有JWKS时用服务端密钥验签）
+func NewHMACKeySet(secret []byte) *JWKS {
+	return &JWKS{keys: map[string]*key{"": {alg: "HS256", oct: secret}}}
+}
+
+func (s *JWKS) lookup(ctx context.Context, kid string) (*key, error) {
+	s.mu.RLock()
+	k := s.find(kid)
+	canRefresh := s.isURL() && time.Since(s.lastRefresh) >= jwksMinRefresh
+	s.mu.RUnlock()
+
+	if k != nil {
+		return k, nil
+	}
+	if canRefresh {
+		if err := s.refresh(ctx); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+		k = s.find(kid)
+		s.mu.RUnlock()
+		if k != nil {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+}
+
+// find 没有kid且集合里只有一个key时直接用它
+func (s *JWKS) find(kid string) *key {
+	if k, ok := s.keys[kid]; ok {
+		return k
+	}
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k
+		}
+	}
+	return nil
+}
+
+func (s *JWKS) isURL() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+func (s *JWKS) refresh(ctx context.Context) error {
+	raw, err := s.read(ctx)
+	if err != nil {
+		return fmt.Errorf("load jwks %s: %w", s.source, err)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("parse jwks %s: %w", s.source, err)
+	}
+
+	keys := make(map[string]*key, len(doc.Keys))
+	for _, j := range doc.Keys {
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		k, err := parseJWK(j)
+		if err != nil {
+			return fmt.Errorf("parse jwk %q: %w", j.Kid, err)
+		}
+		keys[j.Kid] = k
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("jwks %s has no signing keys", s.source)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.lastRefresh = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !s.isURL() {
+		return os.ReadFile(s.source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func parseJWK(j jwk) (*key, error) {
+	k := &key{alg: j.Alg}
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		k.rsa = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		k.ec = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case "oct":
+		b, err := base64.RawURLEncoding.DecodeString(j.K)
+		if err != nil {
+			return nil, err
+		}
+		k.oct = b
+	default:
+		return nil, fmt.Errorf("unsupported kty %s", j.Kty)
+	}
+	return k, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
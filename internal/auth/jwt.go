@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedAlg = errors.New("unsupported jwt algorithm")
+	ErrInvalidClaims  = errors.New("invalid jwt claims")
+)
+
+// Claims JWT里用到的标准声明，其他声明保留在Extra里
+type Claims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // 字符串或字符串数组
+	ExpiresAt int64           `json:"exp"`
+	NotBefore int64           `json:"nbf"`
+
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Strings 读取字符串数组声明，也兼容空格分隔的字符串（如OAuth的scope）
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Extra[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// String 读取字符串声明
+func (c *Claims) String(name string) string {
+	s, _ := c.Extra[name].(string)
+	return s
+}
+
+func (c *Claims) hasAudience(aud string) bool {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return one == aud
+	}
+	var many []string
+	if json.Unmarshal(c.Audience, &many) == nil {
+		for _, a := range many {
+			if a == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verifier 用JWKS验签并检查iss/aud/exp/nbf，Issuer/Audience为空时不检查
+type Verifier struct {
+	Keys     *JWKS
+	Issuer   string
+	Audience string
+	// Leeway 时钟偏差容忍
+	Leeway time.Duration
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// LooksLikeJWT 三段式token才尝试按JWT校验
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify 校验签名和声明，返回claims
+func (v *Verifier) Verify(ctx context.Context, token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var h jwtHeader
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	k, err := v.Keys.lookup(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// key声明了alg时必须一致，防止算法混淆
+	if k.alg != "" && k.alg != h.Alg {
+		return nil, fmt.Errorf("%w: %s for key %q", ErrUnsupportedAlg, h.Alg, h.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := verifySignature(h.Alg, k, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	c, err := parseClaims(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ExpiresAt == 0 || now.Add(-v.Leeway).Unix() >= c.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	if c.NotBefore != 0 && now.Add(v.Leeway).Unix() < c.NotBefore {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidClaims)
+	}
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidClaims, c.Issuer)
+	}
+	if v.Audience != "" && !c.hasAudience(v.Audience) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidClaims)
+	}
+	return c, nil
+}
+
+func verifySignature(alg string, k *key, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "HS256":
+		if k.oct == nil {
+			return fmt.Errorf("%w: HS256 needs a symmetric key", ErrUnsupportedAlg)
+		}
+		mac := hmac.New(sha256.New, k.oct)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrBadSignature
+		}
+	case "RS256":
+		if k.rsa == nil {
+			return fmt.Errorf("%w: RS256 needs an RSA key", ErrUnsupportedAlg)
+		}
+		if rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, digest[:], sig) != nil {
+			return ErrBadSignature
+		}
+	case "ES256":
+		if k.ec == nil {
+			return fmt.Errorf("%w: ES256 needs an EC key", ErrUnsupportedAlg)
+		}
+		// JWS里ES256签名是定长的 r||s
+		if len(sig) != 64 {
+			return ErrBadSignature
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k.ec, digest[:], r, s) {
+			return ErrBadSignature
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+	}
+	return nil
+}
+
+func parseClaims(payload string) (*Claims, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	var c Claims
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := json.Unmarshal(raw, &c.Extra); err != nil {
+		return nil, ErrMalformedToken
+	}
+	return &c, nil
+}
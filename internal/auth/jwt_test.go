@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testHMACKey   = []byte("jwt-test-secret")
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signJWT 按alg签出一个测试用的JWT
+func signJWT(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, testHMACKey)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, testRSAKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("sign RS256: %v", err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, testECKey, digest[:])
+		if err != nil {
+			t.Fatalf("sign ES256: %v", err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	default:
+		sig = []byte("unsigned")
+	}
+	return signed + "." + b64(sig)
+}
+
+// testJWKS RSA和EC公钥各一个，kid分别是rsa-1和ec-1
+func testJWKS(kids ...string) []byte {
+	if len(kids) == 0 {
+		kids = []string{"rsa-1", "ec-1"}
+	}
+	keys := []map[string]string{{
+		"kty": "RSA", "kid": kids[0], "alg": "RS256", "use": "sig",
+		"n": b64(testRSAKey.N.Bytes()), "e": b64(big.NewInt(int64(testRSAKey.E)).Bytes()),
+	}}
+	if len(kids) > 1 {
+		keys = append(keys, map[string]string{
+			"kty": "EC", "kid": kids[1], "crv": "P-256",
+			"x": b64(testECKey.X.FillBytes(make([]byte, 32))), "y": b64(testECKey.Y.FillBytes(make([]byte, 32))),
+		})
+	}
+	// 加密用途的key应被忽略
+	keys = append(keys, map[string]string{"kty": "oct", "kid": "enc-1", "use": "enc", "k": b64([]byte("x"))})
+	doc, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return doc
+}
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, testJWKS(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadJWKS(context.Background(), path)
+	if err != nil {
+		t.Fatalf("LoadJWKS() error = %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "user-1", "iss": "https://issuer.test", "aud": "movies-api",
+			"exp": now.Add(time.Hour).Unix(), "roles": []string{"editor"}, "scope": "movies:write audit:read",
+		}
+		for k, v := range extra {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	valid := signJWT(t, "RS256", "rsa-1", claims(nil))
+	jwks := &Verifier{Keys: keys, Issuer: "https://issuer.test", Audience: "movies-api", Leeway: 30 * time.Second}
+	hmacOnly := &Verifier{Keys: NewHMACKeySet(testHMACKey)}
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+		wantErr  error
+	}{
+		{"RS256", jwks, valid, nil},
+		{"ES256", jwks, signJWT(t, "ES256", "ec-1", claims(nil)), nil},
+		{"HS256 server secret", hmacOnly, signJWT(t, "HS256", "", claims(nil)), nil},
+		{"audience array", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"aud": []string{"other", "movies-api"}})), nil},
+		{"expired within leeway", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})), nil},
+		{"expired", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), ErrTokenExpired},
+		{"missing exp", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"exp": nil})), ErrTokenExpired},
+		{"not yet valid", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), ErrInvalidClaims},
+		{"wrong issuer", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"iss": "https://evil.test"})), ErrInvalidClaims},
+		{"wrong audience", jwks, signJWT(t, "RS256", "rsa-1", claims(map[string]interface{}{"aud": []string{"other"}})), ErrInvalidClaims},
+		{"unknown kid", jwks, signJWT(t, "RS256", "rsa-2", claims(nil)), ErrUnknownKey},
+		// key声明了RS256，header改成HS256（拿公钥当HMAC密钥）必须拒绝
+		{"alg confusion", jwks, signJWT(t, "HS256", "rsa-1", claims(nil)), ErrUnsupportedAlg},
+		{"alg none", jwks, signJWT(t, "none", "ec-1", claims(nil)), ErrUnsupportedAlg},
+		{"HS256 against EC key", jwks, signJWT(t, "HS256", "ec-1", claims(nil)), ErrUnsupportedAlg},
+		{"bad signature", jwks, valid[:len(valid)-4] + "AAAA", ErrBadSignature},
+		{"malformed", jwks, "a.b", ErrMalformedToken},
+		{"bad header", jwks, "!!.e30.c2ln", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.verifier.Verify(context.Background(), tt.token, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.Subject != "user-1" {
+				t.Errorf("Subject = %q, want user-1", c.Subject)
+			}
+			if got := c.Strings("roles"); !slices.Equal(got, []string{"editor"}) {
+				t.Errorf("Strings(roles) = %v, want [editor]", got)
+			}
+			if got := c.Strings("scope"); !slices.Equal(got, []string{"movies:write", "audit:read"}) {
+				t.Errorf("Strings(scope) = %v, want [movies:write audit:read]", got)
+			}
+		})
+	}
+}
+
+func TestJWKSRefreshOnUnknownKid(t *testing.T) {
+	var doc atomic.Value
+	doc.Store(testJWKS("rsa-1"))
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(doc.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	keys, err := LoadJWKS(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("LoadJWKS() error = %v", err)
+	}
+	v := &Verifier{Keys: keys}
+	now := time.Now()
+	token := signJWT(t, "RS256", "rsa-2", map[string]interface{}{"sub": "user-1", "exp": now.Add(time.Hour).Unix()})
+
+	// 密钥轮换：刚拉取过，最小间隔内不重新拉取
+	doc.Store(testJWKS("rsa-2"))
+	if _, err := v.Verify(context.Background(), token, now); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Verify() within min refresh interval error = %v, want %v", err, ErrUnknownKey)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("fetches = %d, want 1", got)
+	}
+
+	// 超过最小间隔后遇到未知kid重新拉取
+	keys.mu.Lock()
+	keys.lastRefresh = time.Now().Add(-jwksMinRefresh)
+	keys.mu.Unlock()
+	if _, err := v.Verify(context.Background(), token, now); err != nil {
+		t.Fatalf("Verify() after rotation error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestLoadJWKSErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"missing file", filepath.Join(dir, "missing.json")},
+		{"not json", write("bad.json", "not json")},
+		{"no signing keys", write("empty.json", `{"keys":[{"kty":"oct","use":"enc","k":"eA"}]}`)},
+		{"unsupported curve", write("curve.json", `{"keys":[{"kty":"EC","crv":"P-384","x":"eA","y":"eA"}]}`)},
+		{"unsupported kty", write("kty.json", `{"keys":[{"kty":"OKP"}]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadJWKS(context.Background(), tt.source); err == nil {
+				t.Errorf("LoadJWKS(%s) error = nil, want error", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken = errors.New("malformed token")
+	ErrBadSignature   = errors.New("invalid token signature")
+	ErrTokenExpired   = errors.New("token expired")
+)
+
+// SignRaterToken 生成签名的rater token，格式：base64url(raterID).expiryUnix.base64url(HMAC-SHA256)
+func SignRaterToken(secret []byte, raterID string, expiresAt time.Time) string {
+	id := base64.RawURLEncoding.EncodeToString([]byte(raterID))
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	sig := base64.RawURLEncoding.EncodeToString(raterMAC(secret, id+"."+exp))
+	return id + "." + exp + "." + sig
+}
+
+// VerifyRaterToken 校验签名和过期时间，返回rater id
+func VerifyRaterToken(secret []byte, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrMalformedToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+	if !hmac.Equal(sig, raterMAC(secret, parts[0]+"."+parts[1])) {
+		return "", ErrBadSignature
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+	if now.Unix() >= exp {
+		return "", ErrTokenExpired
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(id) == 0 {
+		return "", fmt.Errorf("%w: empty rater id", ErrMalformedToken)
+	}
+	return string(id), nil
+}
+
+func raterMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRaterToken(t *testing.T) {
+	secret := []byte("test-secret")
+	now := time.Unix(1_700_000_000, 0)
+	valid := SignRaterToken(secret, "user123", now.Add(time.Hour))
+
+	// 篡改rater id但保留原签名
+	parts := strings.Split(valid, ".")
+	forged := SignRaterToken(secret, "admin", now.Add(time.Hour))
+	forged = strings.Split(forged, ".")[0] + "." + parts[1] + "." + parts[2]
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		now     time.Time
+		wantID  string
+		wantErr error
+	}{
+		{"valid", secret, valid, now, "user123", nil},
+		{"unicode id", secret, SignRaterToken(secret, "用户 42", now.Add(time.Minute)), now, "用户 42", nil},
+		{"expired", secret, valid, now.Add(time.Hour), "", ErrTokenExpired},
+		{"wrong secret", []byte("other"), valid, now, "", ErrBadSignature},
+		{"forged id", secret, forged, now, "", ErrBadSignature},
+		{"two parts", secret, parts[0] + "." + parts[1], now, "", ErrMalformedToken},
+		{"bad signature encoding", secret, parts[0] + "." + parts[1] + ".!!!", now, "", ErrMalformedToken},
+		{"empty id", secret, SignRaterToken(secret, "", now.Add(time.Hour)), now, "", ErrMalformedToken},
+		{"empty token", secret, "", now, "", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := VerifyRaterToken(tt.secret, tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyRaterToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("VerifyRaterToken() id = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+// 角色
+const (
+	RoleViewer    = "viewer"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// 权限（API token的scopes也直接使用这些值）
+const (
+	ScopeMoviesWrite     = "movies:write"
+	ScopeMoviesDelete    = "movies:delete"
+	ScopeReviewsModerate = "reviews:moderate"
+	ScopeRatingsPurge    = "ratings:purge"
+	ScopeReportsRead     = "reports:read"
+	ScopeTokensManage    = "tokens:manage"
+	ScopeAuditRead       = "audit:read"
+	// ScopeAdmin 隐含所有权限，只能通过admin角色获得，不能作为scope直接授予
+	ScopeAdmin = "admin"
+)
+
+// RolePermissions 角色-权限矩阵
+var RolePermissions = map[string][]string{
+	RoleViewer:    {},
+	RoleEditor:    {ScopeMoviesWrite},
+	RoleModerator: {ScopeReviewsModerate, ScopeReportsRead},
+	RoleAdmin:     {ScopeAdmin},
+}
+
+// AllScopes 可以直接授予（API token的scopes、JWT的scope声明）的权限，不含admin
+var AllScopes = []string{
+	ScopeMoviesWrite, ScopeMoviesDelete, ScopeReviewsModerate,
+	ScopeRatingsPurge, ScopeReportsRead, ScopeTokensManage, ScopeAuditRead,
+}
+
+// ValidScope 判断权限是否可以直接授予
+func ValidScope(s string) bool {
+	for _, v := range AllScopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidRole 判断角色是否合法
+func ValidRole(r string) bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// Permissions 角色展开后的权限加上直接授予的scopes；scopes里不在AllScopes中的（包括admin）忽略
+func Permissions(roles, scopes []string) []string {
+	perms := []string{}
+	for _, r := range roles {
+		perms = append(perms, RolePermissions[r]...)
+	}
+	for _, s := range scopes {
+		if ValidScope(s) {
+			perms = append(perms, s)
+		}
+	}
+	return perms
+}
+
+// HasScope admin隐含所有权限
+func HasScope(perms []string, want string) bool {
+	for _, s := range perms {
+		if s == want || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
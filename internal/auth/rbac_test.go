@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []string
+		scopes []string
+		want   []string
+	}{
+		{"nothing", nil, nil, []string{}},
+		{"viewer", []string{RoleViewer}, nil, []string{}},
+		{"editor", []string{RoleEditor}, nil, []string{ScopeMoviesWrite}},
+		{"moderator", []string{RoleModerator}, nil, []string{ScopeReviewsModerate, ScopeReportsRead}},
+		{"admin role", []string{RoleAdmin}, nil, []string{ScopeAdmin}},
+		{"role plus scope", []string{RoleEditor}, []string{ScopeMoviesDelete}, []string{ScopeMoviesWrite, ScopeMoviesDelete}},
+		{"unknown role", []string{"root"}, nil, []string{}},
+		// admin只能通过角色获得，直接作为scope授予时忽略
+		{"admin scope ignored", nil, []string{ScopeAdmin}, []string{}},
+		{"invalid scope ignored", nil, []string{"movies:*", ScopeAuditRead}, []string{ScopeAuditRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Permissions(tt.roles, tt.scopes); !slices.Equal(got, tt.want) {
+				t.Errorf("Permissions(%v, %v) = %v, want %v", tt.roles, tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms []string
+		want  string
+		ok    bool
+	}{
+		{"granted", []string{ScopeMoviesWrite}, ScopeMoviesWrite, true},
+		{"not granted", []string{ScopeMoviesWrite}, ScopeMoviesDelete, false},
+		{"admin implies all", []string{ScopeAdmin}, ScopeTokensManage, true},
+		{"empty", nil, ScopeReportsRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasScope(tt.perms, tt.want); got != tt.ok {
+				t.Errorf("HasScope(%v, %q) = %v, want %v", tt.perms, tt.want, got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidScopeAndRole(t *testing.T) {
+	for _, s := range AllScopes {
+		if !ValidScope(s) {
+			t.Errorf("ValidScope(%q) = false, want true", s)
+		}
+	}
+	if ValidScope(ScopeAdmin) {
+		t.Errorf("ValidScope(%q) = true, want false", ScopeAdmin)
+	}
+	for r := range RolePermissions {
+		if !ValidRole(r) {
+			t.Errorf("ValidRole(%q) = false, want true", r)
+		}
+	}
+	if ValidRole("root") {
+		t.Errorf("ValidRole(%q) = true, want false", "root")
+	}
+}
@@ -0,0 +1,159 @@
+// Package boxofficetest 按boxoffice.openapi.yml实现的假票房上游，
+// 供cmd/boxoffice-mock和测试（httptest）共用
+package boxofficetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"interview"
+)
+
+// Options 假上游的行为配置
+type Options struct {
+	// APIKey 非空时要求请求带匹配的X-API-Key，否则返回401
+	APIKey string
+	// Latency 每个请求固定的延迟，Jitter 在此基础上再加 [0, Jitter) 的随机延迟
+	Latency time.Duration
+	Jitter  time.Duration
+	// FaultRate 以这个概率（0~1）直接返回500，用来测试重试/熔断/降级
+	FaultRate float64
+	// Seed 随机数种子，0表示按当前时间
+	Seed int64
+}
+
+// Handler 提供 GET /boxoffice?title=
+type Handler struct {
+	records map[string]json.RawMessage
+	lower   map[string]string // 小写标题 -> records里的key
+	opts    Options
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// ParseRecords 解析mock-boxoffice.json格式：{ "<title>": {BoxOfficeRecord}, ... }
+func ParseRecords(data []byte) (map[string]json.RawMessage, error) {
+	records := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parse box office records: %w", err)
+	}
+	return records, nil
+}
+
+// LoadRecords 从文件读取记录，path为空时用内嵌的mock-boxoffice.json
+func LoadRecords(path string) (map[string]json.RawMessage, error) {
+	if path == "" {
+		return ParseRecords(interview.MockBoxOffice)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read box office records: %w", err)
+	}
+	return ParseRecords(data)
+}
+
+func NewHandler(records map[string]json.RawMessage, opts Options) *Handler {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	h := &Handler{
+		records: records,
+		lower:   make(map[string]string, len(records)),
+		opts:    opts,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	for title := range records {
+		h.lower[strings.ToLower(title)] = title
+	}
+	return h
+}
+
+// NewServer 用内嵌的mock数据启动一个httptest server，调用方负责Close
+func NewServer(opts Options) *httptest.Server {
+	records, err := ParseRecords(interview.MockBoxOffice)
+	if err != nil {
+		// 内嵌数据是仓库里的文件，解析失败说明文件本身坏了
+		panic(err)
+	}
+	return httptest.NewServer(NewHandler(records, opts))
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if delay := h.delay(); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if r.URL.Path != "/boxoffice" || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotFound, "Not Found", "Unknown endpoint.")
+		return
+	}
+	if h.opts.APIKey != "" && r.Header.Get("X-API-Key") != h.opts.APIKey {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "The API key is missing or invalid.")
+		return
+	}
+	if h.fault() {
+		writeError(w, http.StatusInternalServerError, "Internal Server Error", "Injected fault.")
+		return
+	}
+
+	title := r.URL.Query().Get("title")
+	if strings.TrimSpace(title) == "" {
+		writeError(w, http.StatusBadRequest, "Bad Request", "The 'title' query parameter is required.")
+		return
+	}
+
+	record, ok := h.records[title]
+	if !ok {
+		// 找不到时再按大小写不敏感匹配一次
+		if key, found := h.lower[strings.ToLower(title)]; found {
+			record, ok = h.records[key], true
+		}
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found", "Movie with the specified title was not found.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(record)
+}
+
+func (h *Handler) delay() time.Duration {
+	d := h.opts.Latency
+	if h.opts.Jitter > 0 {
+		h.mu.Lock()
+		d += time.Duration(h.rng.Int63n(int64(h.opts.Jitter)))
+		h.mu.Unlock()
+	}
+	return d
+}
+
+func (h *Handler) fault() bool {
+	if h.opts.FaultRate <= 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rng.Float64() < h.opts.FaultRate
+}
+
+// writeError 返回boxoffice.openapi.yml里的Error结构 {error, message}
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": errType, "message": message})
+}
@@ -0,0 +1,178 @@
+package boxoffice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开时直接返回，不请求上游
+var ErrCircuitOpen = errors.New("upstream_error: circuit open")
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Breaker 按失败率熔断：Window内请求数达到MinRequests且失败率>=FailureRate时打开，
+// CoolDown之后进入半开，放HalfOpenProbes个请求探测，全部成功才关闭，任一失败重新打开
+type Breaker struct {
+	Window         time.Duration
+	MinRequests    int
+	FailureRate    float64
+	CoolDown       time.Duration
+	HalfOpenProbes int
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int // 半开状态已放行的探测数
+	probeOK     int // 半开状态已成功的探测数
+
+	// 统计（给health和metrics用）
+	rejected    int64
+	transitions map[BreakerState]int64
+}
+
+// BreakerStats 熔断器当前状态的快照
+type BreakerStats struct {
+	State       BreakerState
+	Requests    int
+	Failures    int
+	OpenedAt    *time.Time
+	Rejected    int64
+	Transitions map[BreakerState]int64
+}
+
+func NewBreaker(window time.Duration, minRequests int, failureRate float64, coolDown time.Duration) *Breaker {
+	return &Breaker{
+		Window:         window,
+		MinRequests:    minRequests,
+		FailureRate:    failureRate,
+		CoolDown:       coolDown,
+		HalfOpenProbes: 1,
+		transitions:    map[BreakerState]int64{},
+	}
+}
+
+// Allow 判断能否发请求；放行后必须调用Record
+func (b *Breaker) Allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.CoolDown {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen, now)
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.HalfOpenProbes {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record 记录一次请求的结果
+func (b *Breaker) Record(now time.Time, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.setState(StateOpen, now)
+			return
+		}
+		b.probeOK++
+		if b.probeOK >= b.HalfOpenProbes {
+			b.setState(StateClosed, now)
+		}
+	case StateClosed:
+		// 固定窗口计数，窗口过期就重新开始
+		if now.Sub(b.windowStart) > b.Window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.MinRequests && float64(b.failures)/float64(b.requests) >= b.FailureRate {
+			b.setState(StateOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) setState(s BreakerState, now time.Time) {
+	b.state = s
+	b.transitions[s]++
+	b.probes, b.probeOK = 0, 0
+	switch s {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+}
+
+// Stats 当前状态快照
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerStats{
+		State:       b.state,
+		Requests:    b.requests,
+		Failures:    b.failures,
+		Rejected:    b.rejected,
+		Transitions: make(map[BreakerState]int64, len(b.transitions)),
+	}
+	for k, v := range b.transitions {
+		st.Transitions[k] = v
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		st.OpenedAt = &openedAt
+	}
+	return st
+}
+
+// guarded 经过熔断器发一次请求；只有上游自身的问题（5xx、429、超时、连接错误）算失败，404等正常返回不算
+func (c *Client) guarded(ctx context.Context, title string) (*BoxOfficeData, error) {
+	if c.Breaker == nil {
+		return c.fetchOnce(ctx, title)
+	}
+	if err := c.Breaker.Allow(time.Now()); err != nil {
+		return nil, err
+	}
+
+	data, err := c.fetchOnce(ctx, title)
+	failed := err != nil && (retryable(err) || errors.Is(err, context.DeadlineExceeded))
+	c.Breaker.Record(time.Now(), failed)
+	return data, err
+}
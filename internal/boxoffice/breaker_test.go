@@ -0,0 +1,124 @@
+package boxoffice
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	// 每一步：at时刻先Allow，放行后按failed记录结果
+	type step struct {
+		at      time.Duration
+		failed  bool
+		wantErr error
+		state   BreakerState // 这一步之后的状态
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "stays closed below min requests",
+			steps: []step{
+				{0, true, nil, StateClosed},
+				{time.Second, true, nil, StateClosed},
+			},
+		},
+		{
+			name: "opens at failure rate",
+			steps: []step{
+				{0, false, nil, StateClosed},
+				{time.Second, true, nil, StateClosed},
+				{2 * time.Second, true, nil, StateOpen},
+				{3 * time.Second, false, ErrCircuitOpen, StateOpen},
+			},
+		},
+		{
+			name: "stays closed under failure rate",
+			steps: []step{
+				{0, false, nil, StateClosed},
+				{time.Second, false, nil, StateClosed},
+				{2 * time.Second, true, nil, StateClosed},
+				{3 * time.Second, false, nil, StateClosed},
+			},
+		},
+		{
+			name: "window resets counts",
+			steps: []step{
+				{0, true, nil, StateClosed},
+				{time.Second, true, nil, StateClosed},
+				// 超过窗口，前两次失败不再计入
+				{time.Minute, true, nil, StateClosed},
+				{time.Minute + time.Second, false, nil, StateClosed},
+			},
+		},
+		{
+			name: "half-open probe success closes",
+			steps: []step{
+				{0, true, nil, StateClosed},
+				{0, true, nil, StateClosed},
+				{0, true, nil, StateOpen},
+				{29 * time.Second, false, ErrCircuitOpen, StateOpen},
+				{30 * time.Second, false, nil, StateClosed},
+				{31 * time.Second, false, nil, StateClosed},
+			},
+		},
+		{
+			name: "half-open probe failure reopens",
+			steps: []step{
+				{0, true, nil, StateClosed},
+				{0, true, nil, StateClosed},
+				{0, true, nil, StateOpen},
+				{30 * time.Second, true, nil, StateOpen},
+				// 冷却时间从重新打开时算起
+				{59 * time.Second, false, ErrCircuitOpen, StateOpen},
+				{60 * time.Second, false, nil, StateClosed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(30*time.Second, 3, 0.5, 30*time.Second)
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				err := b.Allow(now)
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: Allow() error = %v, want %v", i, err, s.wantErr)
+				}
+				if err == nil {
+					b.Record(now, s.failed)
+				}
+				if got := b.Stats().State; got != s.state {
+					t.Fatalf("step %d: state = %s, want %s", i, got, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenLimitsProbes(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := NewBreaker(time.Minute, 1, 0.5, time.Second)
+	b.Allow(start)
+	b.Record(start, true)
+
+	after := start.Add(time.Second)
+	if err := b.Allow(after); err != nil {
+		t.Fatalf("first probe: Allow() error = %v", err)
+	}
+	// 探测还没有结果时，其他请求继续被拒绝
+	if err := b.Allow(after); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe: Allow() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	st := b.Stats()
+	if st.State != StateHalfOpen || st.Rejected != 1 || st.OpenedAt == nil {
+		t.Errorf("Stats() = %+v, want half-open with 1 rejection and openedAt set", st)
+	}
+	if st.Transitions[StateOpen] != 1 || st.Transitions[StateHalfOpen] != 1 {
+		t.Errorf("Transitions = %v, want one open and one half-open", st.Transitions)
+	}
+}
@@ -0,0 +1,191 @@
+package boxoffice
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache 票房查询结果的缓存，key是规范化的标题（见lookupKey）。data为nil表示上游没有这部电影（负缓存）；过期的条目按未命中处理
+type Cache interface {
+	Get(ctx context.Context, key string) (data *BoxOfficeData, ok bool, err error)
+	Set(ctx context.Context, key string, data *BoxOfficeData, ttl time.Duration) error
+}
+
+// CacheStats 缓存命中统计，导出到/metrics
+type CacheStats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Errors       uint64
+}
+
+// DefaultFetchTimeout 合并后的上游请求默认的超时
+const DefaultFetchTimeout = 10 * time.Second
+
+// Cached 在数据源前面加一层缓存：有结果的缓存TTL，404缓存NegativeTTL，上游出错不缓存。
+// 规范化后同一个标题（见lookupKey）的并发查询只请求一次上游，这次请求不跟随任何一个调用方的取消，只受FetchTimeout限制
+type Cached struct {
+	Provider     Provider
+	Cache        Cache
+	TTL          time.Duration
+	NegativeTTL  time.Duration
+	FetchTimeout time.Duration
+
+	group                              singleflight.Group
+	hits, negativeHits, misses, errors atomic.Uint64
+}
+
+func NewCached(p Provider, cache Cache, ttl, negativeTTL time.Duration) *Cached {
+	return &Cached{Provider: p, Cache: cache, TTL: ttl, NegativeTTL: negativeTTL, FetchTimeout: DefaultFetchTimeout}
+}
+
+func (c *Cached) Name() string {
+	return c.Provider.Name()
+}
+
+type skipCacheKey struct{}
+
+// SkipCache 让这次查询跳过缓存直接请求上游（结果仍会写回缓存），刷新票房时用
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func (c *Cached) FetchFull(ctx context.Context, title string) (*BoxOfficeData, error) {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	key := lookupKey(ctx, title)
+	if !skip {
+		data, ok, err := c.Cache.Get(ctx, key)
+		if err != nil {
+			// 缓存不可用时直接查上游
+			c.errors.Add(1)
+			log.Printf("boxoffice: cache get %q failed: %v", title, err)
+		} else if ok {
+			if data == nil {
+				c.negativeHits.Add(1)
+			} else {
+				c.hits.Add(1)
+			}
+			return data, nil
+		}
+	}
+	c.misses.Add(1)
+
+	flight := key
+	if skip {
+		// 刷新不和普通查询合并，避免拿到刷新前已经在路上的结果
+		flight = "skip:" + key
+	}
+	// 合并的请求可能有多个调用方在等，不能因为第一个调用方取消或超时就让其他人一起失败：
+	// 上游请求只保留ctx里的值（年份、跳过缓存），用自己的超时；每个调用方只按自己的ctx停止等待
+	ch := c.group.DoChan(flight, func() (interface{}, error) {
+		fetchCtx := context.WithoutCancel(ctx)
+		if c.FetchTimeout > 0 {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(fetchCtx, c.FetchTimeout)
+			defer cancel()
+		}
+		data, err := c.Provider.FetchFull(fetchCtx, title)
+		if err != nil {
+			return nil, err
+		}
+		ttl := c.TTL
+		if data == nil {
+			ttl = c.NegativeTTL
+		}
+		if ttl > 0 {
+			if err := c.Cache.Set(fetchCtx, key, data, ttl); err != nil {
+				c.errors.Add(1)
+				log.Printf("boxoffice: cache set %q failed: %v", title, err)
+			}
+		}
+		return data, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		data, _ := res.Val.(*BoxOfficeData)
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stats 当前的命中统计
+func (c *Cached) Stats() CacheStats {
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Errors:       c.errors.Load(),
+	}
+}
+
+// LRUCache 进程内缓存，超过Size条时淘汰最久没用的
+type LRUCache struct {
+	Size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	data      *BoxOfficeData
+	expiresAt time.Time
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{Size: size, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (l *LRUCache) Get(_ context.Context, key string) (*BoxOfficeData, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expiresAt) {
+		l.ll.Remove(el)
+		delete(l.items, key)
+		return nil, false, nil
+	}
+	l.ll.MoveToFront(el)
+	return e.data, true, nil
+}
+
+func (l *LRUCache) Set(_ context.Context, key string, data *BoxOfficeData, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.data, e.expiresAt = data, expiresAt
+		l.ll.MoveToFront(el)
+		return nil
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, data: data, expiresAt: expiresAt})
+	for l.Size > 0 && l.ll.Len() > l.Size {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len 当前缓存的条目数（含已过期但还没被淘汰的）
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
@@ -0,0 +1,259 @@
+package boxoffice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// errCache Get/Set都失败的缓存
+type errCache struct{}
+
+func (errCache) Get(context.Context, string) (*BoxOfficeData, bool, error) {
+	return nil, false, errors.New("cache down")
+}
+
+func (errCache) Set(context.Context, string, *BoxOfficeData, time.Duration) error {
+	return errors.New("cache down")
+}
+
+func TestCached(t *testing.T) {
+	inception := movieData("Inception", 2010)
+	upstreamErr := &StatusError{StatusCode: 503}
+
+	type lookup struct {
+		ctx   func(context.Context) context.Context
+		title string
+	}
+	plain := func(title string) lookup { return lookup{nil, title} }
+
+	tests := []struct {
+		name      string
+		provider  *fakeProvider
+		cache     Cache
+		lookups   []lookup
+		wantCalls int32
+		wantStats CacheStats
+		wantData  bool // 最后一次查询是否有结果
+		wantErr   bool
+	}{
+		{
+			name:      "hit after miss",
+			provider:  &fakeProvider{results: map[string]*BoxOfficeData{"Inception": inception}},
+			lookups:   []lookup{plain("Inception"), plain("Inception")},
+			wantCalls: 1,
+			wantStats: CacheStats{Hits: 1, Misses: 1},
+			wantData:  true,
+		},
+		{
+			// 规范化后相同的标题共用一个条目
+			name:      "normalized key",
+			provider:  &fakeProvider{results: map[string]*BoxOfficeData{"the dark knight": movieData("The Dark Knight", 2008)}},
+			lookups:   []lookup{plain("the dark knight"), plain("Dark Knight, The")},
+			wantCalls: 1,
+			wantStats: CacheStats{Hits: 1, Misses: 1},
+			wantData:  true,
+		},
+		{
+			name:     "year separates remakes",
+			provider: &fakeProvider{results: map[string]*BoxOfficeData{"The Lion King": movieData("The Lion King", 1994)}},
+			lookups: []lookup{
+				{func(ctx context.Context) context.Context { return WithYear(ctx, 1994) }, "The Lion King"},
+				{func(ctx context.Context) context.Context { return WithYear(ctx, 2019) }, "The Lion King"},
+			},
+			wantCalls: 2,
+			wantStats: CacheStats{Misses: 2},
+			wantData:  true,
+		},
+		{
+			name:      "negative cache",
+			provider:  &fakeProvider{},
+			lookups:   []lookup{plain("Unknown"), plain("Unknown")},
+			wantCalls: 1,
+			wantStats: CacheStats{NegativeHits: 1, Misses: 1},
+		},
+		{
+			name:      "errors are not cached",
+			provider:  &fakeProvider{err: upstreamErr},
+			lookups:   []lookup{plain("Inception"), plain("Inception")},
+			wantCalls: 2,
+			wantStats: CacheStats{Misses: 2},
+			wantErr:   true,
+		},
+		{
+			name:     "skip cache",
+			provider: &fakeProvider{results: map[string]*BoxOfficeData{"Inception": inception}},
+			lookups: []lookup{
+				plain("Inception"),
+				{SkipCache, "Inception"},
+				plain("Inception"),
+			},
+			wantCalls: 2,
+			wantStats: CacheStats{Hits: 1, Misses: 2},
+			wantData:  true,
+		},
+		{
+			name:      "cache errors fall back to upstream",
+			provider:  &fakeProvider{results: map[string]*BoxOfficeData{"Inception": inception}},
+			cache:     errCache{},
+			lookups:   []lookup{plain("Inception"), plain("Inception")},
+			wantCalls: 2,
+			wantStats: CacheStats{Misses: 2, Errors: 4},
+			wantData:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := tt.cache
+			if cache == nil {
+				cache = NewLRUCache(10)
+			}
+			c := NewCached(tt.provider, cache, time.Minute, time.Minute)
+
+			var data *BoxOfficeData
+			var err error
+			for _, l := range tt.lookups {
+				ctx := context.Background()
+				if l.ctx != nil {
+					ctx = l.ctx(ctx)
+				}
+				data, err = c.FetchFull(ctx, l.title)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchFull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (data != nil) != tt.wantData {
+				t.Errorf("FetchFull() data = %+v, want data %v", data, tt.wantData)
+			}
+			if got := tt.provider.calls.Load(); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := c.Stats(); got != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestCachedSingleflight(t *testing.T) {
+	p := &fakeProvider{
+		results: map[string]*BoxOfficeData{"Inception": movieData("Inception", 2010)},
+		release: make(chan struct{}),
+	}
+	c := NewCached(p, NewLRUCache(10), time.Minute, time.Minute)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]*BoxOfficeData, callers)
+	errs := make([]error, callers)
+
+	// 第一个调用方很快放弃，不能影响其他还在等的调用方
+	impatient, cancel := context.WithCancel(context.Background())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := c.FetchFull(impatient, "Inception")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("canceled caller error = %v, want %v", err, context.Canceled)
+		}
+	}()
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.FetchFull(context.Background(), "Inception")
+		}()
+	}
+
+	// 等所有调用方都进入等待后再让上游返回
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Misses < callers+1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+
+	if got := p.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+	for i := range callers {
+		if errs[i] != nil || results[i] == nil {
+			t.Errorf("caller %d: FetchFull() = %v, %v; want data", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestCachedFetchTimeout(t *testing.T) {
+	p := &fakeProvider{release: make(chan struct{})}
+	defer close(p.release)
+	c := NewCached(p, NewLRUCache(10), time.Minute, time.Minute)
+	c.FetchTimeout = 20 * time.Millisecond
+
+	_, err := c.FetchFull(context.Background(), "Inception")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FetchFull() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	a, b := movieData("A", 2000), movieData("B", 2000)
+
+	type op struct {
+		set  bool
+		key  string
+		data *BoxOfficeData
+		ttl  time.Duration
+		// Get的期望
+		want   *BoxOfficeData
+		wantOK bool
+	}
+	set := func(key string, data *BoxOfficeData, ttl time.Duration) op {
+		return op{set: true, key: key, data: data, ttl: ttl}
+	}
+	get := func(key string, want *BoxOfficeData, ok bool) op { return op{key: key, want: want, wantOK: ok} }
+
+	tests := []struct {
+		name    string
+		ops     []op
+		wantLen int
+	}{
+		{"hit", []op{set("a", a, time.Minute), get("a", a, true)}, 1},
+		{"miss", []op{get("a", nil, false)}, 0},
+		{"negative entry", []op{set("a", nil, time.Minute), get("a", nil, true)}, 1},
+		{"expired", []op{set("a", a, -time.Second), get("a", nil, false)}, 0},
+		{"overwrite", []op{set("a", a, time.Minute), set("a", b, time.Minute), get("a", b, true)}, 1},
+		{
+			// 访问过a之后b是最久没用的，写入c时淘汰b
+			name: "evicts least recently used",
+			ops: []op{
+				set("a", a, time.Minute), set("b", b, time.Minute), get("a", a, true), set("c", nil, time.Minute),
+				get("b", nil, false), get("a", a, true), get("c", nil, true),
+			},
+			wantLen: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			l := NewLRUCache(2)
+			for i, o := range tt.ops {
+				if o.set {
+					l.Set(ctx, o.key, o.data, o.ttl)
+					continue
+				}
+				data, ok, err := l.Get(ctx, o.key)
+				if err != nil || ok != o.wantOK || data != o.want {
+					t.Errorf("op %d: Get(%q) = %v, %v, %v; want %v, %v, nil", i, o.key, data, ok, err, o.want, o.wantOK)
+				}
+			}
+			if got := l.Len(); got != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
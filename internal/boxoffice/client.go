@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"interview/internal/currency"
 	"interview/internal/domain"
 )
 
@@ -15,17 +18,37 @@ import (
 type Client struct {
 	BaseURL string
 	APIKey  string
-	Client  *http.Client
+	// Source 数据源名字，写入BoxOffice.Source和BoxOfficeData.Sources
+	Source string
+	// Client 单次请求的超时在这里设置，总耗时由调用方的ctx决定
+	Client *http.Client
+
+	// 重试：5xx、超时、连接错误和429会重试，退避时间为 [0, min(RetryMax, RetryBase*2^n)) 的随机值
+	MaxRetries int
+	RetryBase  time.Duration
+	RetryMax   time.Duration
+
+	// Breaker 熔断器，nil表示不启用
+	Breaker *Breaker
+	// Rates 把非USD的预算换算成domain.BudgetCurrency，nil时只接受USD的预算
+	Rates *currency.Table
 }
 
+// DefaultSource 只配置了BOXOFFICE_URL时唯一数据源的名字
+const DefaultSource = "ExampleBoxOfficeAPI"
+
 // 创建新的BoxOffice客户端
 func New(baseURL, key string) *Client {
 	return &Client{
 		BaseURL: baseURL,
 		APIKey:  key,
+		Source:  DefaultSource,
 		Client: &http.Client{
 			Timeout: 3 * time.Second,
 		},
+		MaxRetries: 2,
+		RetryBase:  100 * time.Millisecond,
+		RetryMax:   time.Second,
 	}
 }
 
@@ -40,14 +63,33 @@ type responseDTO struct {
 		OpeningWeekendUSA int64 `json:"openingWeekendUSA"`
 	} `json:"revenue"`
 	MpaRating string `json:"mpaRating"`
+	// Currency 票房金额的币种，上游没给时按USD
+	Currency string `json:"currency"`
 }
 
 // BoxOfficeData 扩展的票房数据，包含额外字段
 type BoxOfficeData struct {
-	BoxOffice   *domain.BoxOffice
-	Distributor *string
-	Budget      *int64
-	MpaRating   *string
+	BoxOffice   *domain.BoxOffice `json:"boxOffice,omitempty"`
+	Distributor *string           `json:"distributor,omitempty"`
+	Budget      *int64            `json:"budget,omitempty"`
+	MpaRating   *string           `json:"mpaRating,omitempty"`
+	// Title/ReleaseDate 上游的规范标题和北美上映日期，用来和用户填的对比（见internal/reconcile）
+	Title       string     `json:"title,omitempty"`
+	ReleaseDate *time.Time `json:"releaseDate,omitempty"`
+	// Sources 每个非空字段来自哪个数据源，key见FieldBoxOffice等常量
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+// parseReleaseDate 上游按契约返回2010-07-16，也兼容RFC3339
+func parseReleaseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
 }
 
 // Fetch 查询票房信息
@@ -59,8 +101,18 @@ func (c *Client) Fetch(ctx context.Context, title string) (*domain.BoxOffice, er
 	return data.BoxOffice, nil
 }
 
-// FetchFull 查询完整的票房信息
+// Name 实现Provider
+func (c *Client) Name() string {
+	return c.Source
+}
+
+// FetchFull 查询完整的票房信息，可重试的错误按退避策略重试（见retry.go），熔断打开时直接返回ErrCircuitOpen
 func (c *Client) FetchFull(ctx context.Context, title string) (*BoxOfficeData, error) {
+	return c.withRetry(ctx, title, c.guarded)
+}
+
+// fetchOnce 发一次请求
+func (c *Client) fetchOnce(ctx context.Context, title string) (*BoxOfficeData, error) {
 	u, _ := url.Parse(c.BaseURL)
 	u.Path = "/boxoffice"
 
@@ -83,7 +135,10 @@ func (c *Client) FetchFull(ctx context.Context, title string) (*BoxOfficeData, e
 	case http.StatusOK:
 
 	default:
-		return nil, fmt.Errorf("upstream_error: status %d", resp.StatusCode)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	var dto responseDTO
@@ -91,7 +146,17 @@ func (c *Client) FetchFull(ctx context.Context, title string) (*BoxOfficeData, e
 		return nil, fmt.Errorf("decode_error: %w", err)
 	}
 
-	t, _ := time.Parse(time.RFC3339, dto.ReleaseDate)
+	// 上游没有单独的更新时间，沿用上映日期作为lastUpdated；日期缺失或格式不对时用拉取时间
+	releaseDate, ok := parseReleaseDate(dto.ReleaseDate)
+	t := releaseDate
+	if !ok {
+		t = time.Now().UTC()
+	}
+
+	cur := strings.ToUpper(strings.TrimSpace(dto.Currency))
+	if cur == "" {
+		cur = "USD"
+	}
 
 	result := &BoxOfficeData{
 		BoxOffice: &domain.BoxOffice{
@@ -99,22 +164,49 @@ func (c *Client) FetchFull(ctx context.Context, title string) (*BoxOfficeData, e
 				Worldwide:         dto.Revenue.Worldwide,
 				OpeningWeekendUSA: dto.Revenue.OpeningWeekendUSA,
 			},
-			Currency:    "USD",
-			Source:      "ExampleBoxOfficeAPI",
+			Currency:    cur,
+			Source:      c.Source,
 			LastUpdated: t,
 		},
+		Title:   strings.TrimSpace(dto.Title),
+		Sources: map[string]string{FieldBoxOffice: c.Source},
+	}
+	if ok {
+		result.ReleaseDate = &releaseDate
 	}
 
 	// 添加额外字段
 	if dto.Distributor != "" {
 		result.Distributor = &dto.Distributor
+		result.Sources[FieldDistributor] = c.Source
 	}
 	if dto.Budget > 0 {
-		result.Budget = &dto.Budget
+		if budget, ok := c.budget(dto.Budget, cur, t); ok {
+			result.Budget = &budget
+			result.Sources[FieldBudget] = c.Source
+		}
 	}
 	if dto.MpaRating != "" {
 		result.MpaRating = &dto.MpaRating
+		result.Sources[FieldMpaRating] = c.Source
 	}
 
 	return result, nil
 }
+
+// budget 把上游的预算按上映日期的汇率换算成domain.BudgetCurrency；没有汇率时不要这个预算，免得把别的币种的金额当成美元存
+func (c *Client) budget(amount int64, from string, at time.Time) (int64, bool) {
+	if from == domain.BudgetCurrency {
+		return amount, true
+	}
+	if c.Rates == nil {
+		log.Printf("boxoffice: %s: dropping budget in %s, no exchange rates", c.Source, from)
+		return 0, false
+	}
+	q, err := c.Rates.Quote(from, domain.BudgetCurrency, at)
+	if err != nil {
+		log.Printf("boxoffice: %s: dropping budget in %s: %v", c.Source, from, err)
+		return 0, false
+	}
+	return q.Apply(amount), true
+}
@@ -0,0 +1,66 @@
+package boxoffice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"interview/internal/currency"
+)
+
+func TestFetchBudgetCurrency(t *testing.T) {
+	rates, err := currency.Parse(strings.NewReader("date,currency,rate\n2010-01-01,EUR,0.75\n"))
+	if err != nil {
+		t.Fatalf("currency.Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		currency    string
+		releaseDate string
+		rates       *currency.Table
+		wantBudget  int64 // 0表示没有预算
+	}{
+		{"usd", "USD", "2010-07-16", nil, 160000000},
+		{"missing currency defaults to usd", "", "2010-07-16", nil, 160000000},
+		{"converted to usd", "eur", "2010-07-16", rates, 213333333},
+		{"no exchange rates", "EUR", "2010-07-16", nil, 0},
+		{"unknown currency", "XYZ", "2010-07-16", rates, 0},
+		{"no rate on the release date", "EUR", "2009-07-16", rates, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"title":"Inception","releaseDate":%q,"budget":160000000,"currency":%q,
+					"revenue":{"worldwide":829895144,"openingWeekendUSA":62785337}}`, tt.releaseDate, tt.currency)
+			}))
+			defer srv.Close()
+
+			c := New(srv.URL, "key")
+			c.MaxRetries = 0
+			c.Rates = tt.rates
+
+			data, err := c.FetchFull(context.Background(), "Inception")
+			if err != nil {
+				t.Fatalf("FetchFull() error = %v", err)
+			}
+			var got int64
+			if data.Budget != nil {
+				got = *data.Budget
+			}
+			if got != tt.wantBudget {
+				t.Errorf("Budget = %d, want %d", got, tt.wantBudget)
+			}
+			if _, ok := data.Sources[FieldBudget]; ok != (tt.wantBudget != 0) {
+				t.Errorf("Sources = %v, want budget source %v", data.Sources, tt.wantBudget != 0)
+			}
+			// 票房金额保持上游的币种
+			if want := strings.ToUpper(tt.currency); want != "" && data.BoxOffice.Currency != want {
+				t.Errorf("BoxOffice.Currency = %q, want %q", data.BoxOffice.Currency, want)
+			}
+		})
+	}
+}
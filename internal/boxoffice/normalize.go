@@ -0,0 +1,91 @@
+package boxoffice
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"interview/internal/titles"
+)
+
+type yearKey struct{}
+
+// WithYear 给这次查询加上映年份，同名的电影（翻拍）按年份挑
+func WithYear(ctx context.Context, year int) context.Context {
+	return context.WithValue(ctx, yearKey{}, year)
+}
+
+// lookupYear ctx里的年份优先，其次是标题末尾的(年份)
+func lookupYear(ctx context.Context, title string) int {
+	if year, _ := ctx.Value(yearKey{}).(int); year > 0 {
+		return year
+	}
+	_, year := titles.Split(title)
+	return year
+}
+
+// lookupKey 规范化的标题加年份，写法不同的同一部电影共用一个缓存条目
+func lookupKey(ctx context.Context, title string) string {
+	key := titles.Key(title)
+	if year := lookupYear(ctx, title); year > 0 {
+		key += " (" + strconv.Itoa(year) + ")"
+	}
+	return key
+}
+
+// Normalized 上游按原始标题查不到合适结果时，按titles.Alternate换一种写法再查一次
+// （"Dark Knight, The" -> "The Dark Knight"，原标题查到的年份对不上时再试 "The Lion King (2019)"），每次最多两次上游查询。
+// 上游返回的标题规范化后要和查询的一致；有年份时上映年份差一年以上的结果（同名的翻拍）不要
+type Normalized struct {
+	Provider Provider
+}
+
+func NewNormalized(p Provider) *Normalized {
+	return &Normalized{Provider: p}
+}
+
+func (n *Normalized) Name() string {
+	return n.Provider.Name()
+}
+
+func (n *Normalized) FetchFull(ctx context.Context, title string) (*BoxOfficeData, error) {
+	key := titles.Key(title)
+	year := lookupYear(ctx, title)
+
+	data, err := n.Provider.FetchFull(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	if matches(data, key, year) {
+		return data, nil
+	}
+	alt := titles.Alternate(title, year, data != nil)
+	if alt == "" {
+		return nil, nil
+	}
+	data, err = n.Provider.FetchFull(ctx, alt)
+	if err != nil {
+		return nil, err
+	}
+	if matches(data, key, year) {
+		return data, nil
+	}
+	return nil, nil
+}
+
+// matches 上游结果是不是要找的这部：规范化标题一致，有年份时上映年份差一年以内
+func matches(data *BoxOfficeData, key string, year int) bool {
+	if data == nil {
+		return false
+	}
+	if data.Title != "" && titles.Key(data.Title) != key {
+		log.Printf("boxoffice: %q matched upstream title %q, skipping", key, data.Title)
+		return false
+	}
+	if year > 0 && data.ReleaseDate != nil {
+		if diff := data.ReleaseDate.Year() - year; diff > 1 || diff < -1 {
+			return false
+		}
+	}
+	return true
+}
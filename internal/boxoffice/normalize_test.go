@@ -0,0 +1,107 @@
+package boxoffice
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNormalized(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     map[string]*BoxOfficeData
+		title       string
+		year        int
+		wantTitle   string // 空表示没有结果
+		wantQueries []string
+	}{
+		{
+			name:        "direct hit",
+			results:     map[string]*BoxOfficeData{"Inception": movieData("Inception", 2010)},
+			title:       "Inception",
+			wantTitle:   "Inception",
+			wantQueries: []string{"Inception"},
+		},
+		{
+			name:        "trailing article",
+			results:     map[string]*BoxOfficeData{"The Dark Knight": movieData("The Dark Knight", 2008)},
+			title:       "Dark Knight, The",
+			wantTitle:   "The Dark Knight",
+			wantQueries: []string{"Dark Knight, The", "The Dark Knight"},
+		},
+		{
+			// 原标题查到的是1994年版，带上年份再查一次
+			name: "remake by year",
+			results: map[string]*BoxOfficeData{
+				"The Lion King":        movieData("The Lion King", 1994),
+				"The Lion King (2019)": movieData("The Lion King", 2019),
+			},
+			title:       "The Lion King",
+			year:        2019,
+			wantTitle:   "The Lion King",
+			wantQueries: []string{"The Lion King", "The Lion King (2019)"},
+		},
+		{
+			name:        "year within one year",
+			results:     map[string]*BoxOfficeData{"Inception": movieData("Inception", 2010)},
+			title:       "Inception",
+			year:        2011,
+			wantTitle:   "Inception",
+			wantQueries: []string{"Inception"},
+		},
+		{
+			name:        "wrong year and no alternate",
+			results:     map[string]*BoxOfficeData{"Inception": movieData("Inception", 2010)},
+			title:       "Inception",
+			year:        1990,
+			wantQueries: []string{"Inception", "Inception (1990)"},
+		},
+		{
+			// 上游模糊匹配到了另一部电影
+			name:        "different upstream title",
+			results:     map[string]*BoxOfficeData{"Alien": movieData("Aliens", 1986)},
+			title:       "Alien",
+			wantQueries: []string{"Alien"},
+		},
+		{
+			name:        "not found",
+			title:       "Unknown Movie",
+			wantQueries: []string{"Unknown Movie"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{results: tt.results}
+			ctx := context.Background()
+			if tt.year > 0 {
+				ctx = WithYear(ctx, tt.year)
+			}
+
+			data, err := NewNormalized(p).FetchFull(ctx, tt.title)
+			if err != nil {
+				t.Fatalf("FetchFull() error = %v", err)
+			}
+			got := ""
+			if data != nil {
+				got = data.Title
+			}
+			if got != tt.wantTitle {
+				t.Errorf("FetchFull() title = %q, want %q", got, tt.wantTitle)
+			}
+			if !slices.Equal(p.queries, tt.wantQueries) {
+				t.Errorf("upstream queries = %q, want %q", p.queries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestNormalizedUpstreamError(t *testing.T) {
+	p := &fakeProvider{err: &StatusError{StatusCode: 503}}
+	if _, err := NewNormalized(p).FetchFull(context.Background(), "Dark Knight, The"); err == nil {
+		t.Fatalf("FetchFull() error = nil, want upstream error")
+	}
+	// 出错时不再换写法重试，交给重试和熔断处理
+	if got := p.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+}
@@ -0,0 +1,102 @@
+package boxoffice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresCache 存在boxoffice_cache表里的缓存，多个实例共享；title列存的是规范化后的key。
+// Namespace区分不同的数据源配置（用数据源名字），避免配置不同的实例读到彼此的结果
+type PostgresCache struct {
+	DB        *sqlx.DB
+	Namespace string
+}
+
+func NewPostgresCache(db *sqlx.DB, namespace string) *PostgresCache {
+	return &PostgresCache{DB: db, Namespace: namespace}
+}
+
+func (p *PostgresCache) Get(ctx context.Context, key string) (*BoxOfficeData, bool, error) {
+	var raw []byte
+	err := p.DB.QueryRowContext(ctx, `
+		SELECT data FROM boxoffice_cache
+		WHERE namespace = $1 AND title = $2 AND expires_at > NOW()
+	`, p.Namespace, key).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read box office cache: %w", err)
+	}
+	// data为NULL是负缓存
+	if raw == nil {
+		return nil, true, nil
+	}
+	var data BoxOfficeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, false, fmt.Errorf("decode box office cache: %w", err)
+	}
+	return &data, true, nil
+}
+
+func (p *PostgresCache) Set(ctx context.Context, key string, data *BoxOfficeData, ttl time.Duration) error {
+	// pq会把[]byte当bytea发送，所以JSONB用string传
+	var value *string
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("encode box office cache: %w", err)
+		}
+		s := string(b)
+		value = &s
+	}
+	_, err := p.DB.ExecContext(ctx, `
+		INSERT INTO boxoffice_cache (namespace, title, data, expires_at)
+		VALUES ($1, $2, $3::jsonb, NOW() + $4::float8 * INTERVAL '1 second')
+		ON CONFLICT (namespace, title)
+		DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at, updated_at = NOW()
+	`, p.Namespace, key, value, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("write box office cache: %w", err)
+	}
+	return nil
+}
+
+// Purge 删除已过期的条目，返回删除的条数
+func (p *PostgresCache) Purge(ctx context.Context) (int64, error) {
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM boxoffice_cache WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, fmt.Errorf("purge box office cache: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// StartPurge 每隔interval清理一次过期条目，ctx取消后退出
+func (p *PostgresCache) StartPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			n, err := p.Purge(ctx)
+			if err != nil {
+				log.Printf("boxoffice: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("boxoffice: purged %d expired cache entries", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,226 @@
+package boxoffice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"interview/internal/currency"
+)
+
+// Provider 票房数据源。上游没有这部电影时返回 (nil, nil)
+type Provider interface {
+	Name() string
+	FetchFull(ctx context.Context, title string) (*BoxOfficeData, error)
+}
+
+// BoxOfficeData.Sources 的字段名
+const (
+	FieldDistributor = "distributor"
+	FieldBudget      = "budget"
+	FieldMpaRating   = "mpaRating"
+	FieldBoxOffice   = "boxOffice"
+)
+
+// ProviderConfig 创建数据源需要的配置，重试和熔断参数只对HTTP类数据源有意义
+type ProviderConfig struct {
+	Name   string
+	Kind   string
+	URL    string
+	APIKey string
+
+	AttemptTimeout time.Duration
+	MaxRetries     int
+	RetryBase      time.Duration
+	RetryMax       time.Duration
+	Breaker        *Breaker
+	// Rates 换算上游预算用的汇率表
+	Rates *currency.Table
+}
+
+// Factory 按配置创建数据源
+type Factory func(cfg ProviderConfig) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// KindExample 对接boxoffice.openapi.yml描述的上游（ExampleBoxOfficeAPI）
+const KindExample = "example"
+
+func init() {
+	Register(KindExample, func(cfg ProviderConfig) (Provider, error) {
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("provider %s: url is required", cfg.Name)
+		}
+		c := New(cfg.URL, cfg.APIKey)
+		c.Source = cfg.Name
+		if cfg.AttemptTimeout > 0 {
+			c.Client.Timeout = cfg.AttemptTimeout
+		}
+		c.MaxRetries = cfg.MaxRetries
+		c.RetryBase = cfg.RetryBase
+		c.RetryMax = cfg.RetryMax
+		c.Breaker = cfg.Breaker
+		c.Rates = cfg.Rates
+		return c, nil
+	})
+}
+
+// Register 注册一种数据源，kind重复注册会覆盖
+func Register(kind string, f Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[kind] = f
+}
+
+// Kinds 已注册的数据源类型
+func Kinds() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	kinds := make([]string, 0, len(factories))
+	for k := range factories {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewProvider 按kind创建一个数据源
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+	factoriesMu.RLock()
+	f, ok := factories[cfg.Kind]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("provider %s: unknown kind %q (available: %s)", cfg.Name, cfg.Kind, strings.Join(Kinds(), ", "))
+	}
+	return f(cfg)
+}
+
+// Composite 的合并方式
+const (
+	// MergePriority 按顺序查询，用第一个有结果的数据源
+	MergePriority = "priority"
+	// MergeFields 并发查询所有数据源，每个字段取优先级最高的非空值
+	MergeFields = "merge"
+)
+
+// Composite 组合多个数据源，Providers按优先级从高到低排列
+type Composite struct {
+	Providers []Provider
+	Mode      string
+}
+
+func NewComposite(mode string, providers ...Provider) *Composite {
+	return &Composite{Providers: providers, Mode: mode}
+}
+
+func (c *Composite) Name() string {
+	names := make([]string, len(c.Providers))
+	for i, p := range c.Providers {
+		names[i] = p.Name()
+	}
+	return c.Mode + "(" + strings.Join(names, ",") + ")"
+}
+
+// FetchFull 单个数据源失败不影响其他数据源；没有任何结果时只要有数据源出错就返回错误，
+// 不能把"出错的数据源可能有这部电影"当成404
+func (c *Composite) FetchFull(ctx context.Context, title string) (*BoxOfficeData, error) {
+	if c.Mode == MergeFields {
+		return c.merge(ctx, title)
+	}
+	return c.priority(ctx, title)
+}
+
+func (c *Composite) priority(ctx context.Context, title string) (*BoxOfficeData, error) {
+	var errs []error
+	for _, p := range c.Providers {
+		data, err := p.FetchFull(ctx, title)
+		if err != nil {
+			log.Printf("boxoffice: provider %s failed for %q: %v", p.Name(), title, err)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+		if data != nil {
+			return data, nil
+		}
+	}
+	// 所有数据源都明确返回404才算没找到
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, nil
+}
+
+func (c *Composite) merge(ctx context.Context, title string) (*BoxOfficeData, error) {
+	results := make([]*BoxOfficeData, len(c.Providers))
+	errs := make([]error, len(c.Providers))
+
+	var wg sync.WaitGroup
+	for i, p := range c.Providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			results[i], errs[i] = p.FetchFull(ctx, title)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var failed []error
+	merged := &BoxOfficeData{Sources: map[string]string{}}
+	for i, data := range results {
+		if errs[i] != nil {
+			log.Printf("boxoffice: provider %s failed for %q: %v", c.Providers[i].Name(), title, errs[i])
+			failed = append(failed, fmt.Errorf("%s: %w", c.Providers[i].Name(), errs[i]))
+			continue
+		}
+		if data == nil {
+			continue
+		}
+		// 票房作为一个整体取值，不同数据源的金额、币种和更新时间不混在一起
+		if merged.BoxOffice == nil && data.BoxOffice != nil {
+			merged.BoxOffice = data.BoxOffice
+			merged.Sources[FieldBoxOffice] = sourceOf(data, FieldBoxOffice, c.Providers[i])
+		}
+		if merged.Distributor == nil && data.Distributor != nil {
+			merged.Distributor = data.Distributor
+			merged.Sources[FieldDistributor] = sourceOf(data, FieldDistributor, c.Providers[i])
+		}
+		if merged.Budget == nil && data.Budget != nil {
+			merged.Budget = data.Budget
+			merged.Sources[FieldBudget] = sourceOf(data, FieldBudget, c.Providers[i])
+		}
+		if merged.MpaRating == nil && data.MpaRating != nil {
+			merged.MpaRating = data.MpaRating
+			merged.Sources[FieldMpaRating] = sourceOf(data, FieldMpaRating, c.Providers[i])
+		}
+		if merged.Title == "" {
+			merged.Title = data.Title
+		}
+		if merged.ReleaseDate == nil {
+			merged.ReleaseDate = data.ReleaseDate
+		}
+	}
+
+	if len(merged.Sources) == 0 {
+		if len(failed) > 0 {
+			return nil, errors.Join(failed...)
+		}
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// sourceOf 数据源没有记录来源时用数据源自己的名字
+func sourceOf(data *BoxOfficeData, field string, p Provider) string {
+	if s := data.Sources[field]; s != "" {
+		return s
+	}
+	return p.Name()
+}
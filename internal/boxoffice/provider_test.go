@@ -0,0 +1,118 @@
+package boxoffice
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"interview/internal/domain"
+)
+
+// fakeProvider 按标题返回固定结果，记录每次查询；release不为nil时查询会等到它被关闭
+type fakeProvider struct {
+	results map[string]*BoxOfficeData
+	err     error
+	release chan struct{}
+
+	calls   atomic.Int32
+	mu      sync.Mutex
+	queries []string
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) FetchFull(ctx context.Context, title string) (*BoxOfficeData, error) {
+	f.calls.Add(1)
+	f.mu.Lock()
+	f.queries = append(f.queries, title)
+	f.mu.Unlock()
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.results[title], nil
+}
+
+func movieData(title string, year int) *BoxOfficeData {
+	released := time.Date(year, 7, 16, 0, 0, 0, 0, time.UTC)
+	return &BoxOfficeData{
+		Title:       title,
+		ReleaseDate: &released,
+		BoxOffice:   &domain.BoxOffice{Revenue: domain.BoxOfficeRevenue{Worldwide: 100}, Currency: "USD"},
+	}
+}
+
+// namedProvider 给fakeProvider一个自己的名字，Composite按名字记录来源
+type namedProvider struct {
+	*fakeProvider
+	name string
+}
+
+func (n namedProvider) Name() string { return n.name }
+
+func TestComposite(t *testing.T) {
+	budget, distributor := int64(160000000), "Warner Bros."
+	full := movieData("Inception", 2010)
+	partial := &BoxOfficeData{Title: "Inception", Budget: &budget, Distributor: &distributor}
+	upstreamErr := &StatusError{StatusCode: 503}
+
+	found := func(data *BoxOfficeData) *fakeProvider {
+		return &fakeProvider{results: map[string]*BoxOfficeData{"Inception": data}}
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		primary     *fakeProvider
+		backup      *fakeProvider
+		wantErr     bool
+		wantData    bool
+		wantSources map[string]string
+		wantBackup  int32 // backup被查询的次数
+	}{
+		{"priority first wins", MergePriority, found(full), found(partial), false, true, nil, 0},
+		{"priority falls through 404", MergePriority, &fakeProvider{}, found(partial), false, true, nil, 1},
+		{"priority falls through error", MergePriority, &fakeProvider{err: upstreamErr}, found(partial), false, true, nil, 1},
+		{"priority all 404", MergePriority, &fakeProvider{}, &fakeProvider{}, false, false, nil, 1},
+		// 有数据源出错又都没结果时不能当成404
+		{"priority error and 404", MergePriority, &fakeProvider{err: upstreamErr}, &fakeProvider{}, true, false, nil, 1},
+		{
+			"merge fields", MergeFields, found(full), found(partial), false, true,
+			map[string]string{FieldBoxOffice: "primary", FieldBudget: "backup", FieldDistributor: "backup"}, 1,
+		},
+		{
+			"merge ignores failed source", MergeFields, &fakeProvider{err: upstreamErr}, found(partial), false, true,
+			map[string]string{FieldBudget: "backup", FieldDistributor: "backup"}, 1,
+		},
+		{"merge error and 404", MergeFields, &fakeProvider{err: upstreamErr}, &fakeProvider{}, true, false, nil, 1},
+		{"merge all 404", MergeFields, &fakeProvider{}, &fakeProvider{}, false, false, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewComposite(tt.mode, namedProvider{tt.primary, "primary"}, namedProvider{tt.backup, "backup"})
+			data, err := c.FetchFull(context.Background(), "Inception")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchFull() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (data != nil) != tt.wantData {
+				t.Fatalf("FetchFull() data = %+v, want data %v", data, tt.wantData)
+			}
+			if got := tt.backup.calls.Load(); got != tt.wantBackup {
+				t.Errorf("backup calls = %d, want %d", got, tt.wantBackup)
+			}
+			for field, want := range tt.wantSources {
+				if got := data.Sources[field]; got != want {
+					t.Errorf("Sources[%s] = %q, want %q", field, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package boxoffice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// StatusError 上游返回了非200/404的状态码
+type StatusError struct {
+	StatusCode int
+	// RetryAfter 429/503时上游给的Retry-After，没有则为0
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream_error: status %d", e.StatusCode)
+}
+
+// withRetry 执行fetch，遇到可重试的错误时退避后重试；
+// 下一次等待会超过ctx的deadline时直接返回最后一次的错误
+func (c *Client) withRetry(ctx context.Context, title string,
+	fetch func(context.Context, string) (*BoxOfficeData, error)) (*BoxOfficeData, error) {
+
+	for attempt := 0; ; attempt++ {
+		data, err := fetch(ctx, title)
+		if err == nil || attempt >= c.MaxRetries || ctx.Err() != nil || !retryable(err) {
+			return data, err
+		}
+
+		wait := c.backoff(attempt)
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			wait = se.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, err
+		}
+
+		log.Printf("boxoffice: attempt %d for %q failed: %v, retrying in %s", attempt+1, title, err, wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 指数退避 + full jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.RetryBase << attempt
+	if ceiling <= 0 || ceiling > c.RetryMax {
+		ceiling = c.RetryMax
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// retryable 5xx、429、超时和连接被重置/拒绝可以重试；4xx和解析错误重试也没用
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// parseRetryAfter 支持秒数和HTTP日期两种格式
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package boxoffice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"500", &StatusError{StatusCode: 500}, true},
+		{"503 wrapped", fmt.Errorf("fetch: %w", &StatusError{StatusCode: 503}), true},
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"400", &StatusError{StatusCode: 400}, false},
+		{"401", &StatusError{StatusCode: 401}, false},
+		{"timeout", fmt.Errorf("upstream_error: %w", os.ErrDeadlineExceeded), true},
+		{"connection reset", fmt.Errorf("upstream_error: %w", syscall.ECONNRESET), true},
+		{"connection refused", fmt.Errorf("upstream_error: %w", syscall.ECONNREFUSED), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"decode error", errors.New("decode_error: invalid character"), false},
+		{"canceled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(2 * time.Minute).Format(http.TimeFormat), 2 * time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{RetryBase: 100 * time.Millisecond, RetryMax: time.Second}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		// 移位溢出时也不超过RetryMax
+		{70, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			for range 100 {
+				if got := c.backoff(tt.attempt); got < 0 || got >= tt.ceiling {
+					t.Fatalf("backoff(%d) = %s, want [0, %s)", tt.attempt, got, tt.ceiling)
+				}
+			}
+		})
+	}
+	if got := (&Client{}).backoff(3); got != 0 {
+		t.Errorf("backoff without base = %s, want 0", got)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	unavailable := &StatusError{StatusCode: 503}
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error // 每次调用的返回，用完后返回成功
+		timeout    time.Duration
+		wantCalls  int
+		wantErr    error
+	}{
+		{"success", 2, nil, 0, 1, nil},
+		{"retry then success", 2, []error{unavailable, unavailable}, 0, 3, nil},
+		{"retries exhausted", 2, []error{unavailable, unavailable, unavailable, unavailable}, 0, 3, unavailable},
+		{"not retryable", 2, []error{&StatusError{StatusCode: 400}}, 0, 1, &StatusError{StatusCode: 400}},
+		// Retry-After超过剩余时间时不再等待
+		{"retry after beyond deadline", 2, []error{&StatusError{StatusCode: 429, RetryAfter: time.Minute}}, time.Second, 1, &StatusError{StatusCode: 429}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{MaxRetries: tt.maxRetries, RetryBase: time.Millisecond, RetryMax: time.Millisecond}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			calls := 0
+			data, err := c.withRetry(ctx, "Inception", func(context.Context, string) (*BoxOfficeData, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+				return &BoxOfficeData{Title: "Inception"}, nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			var se, wantSE *StatusError
+			switch {
+			case tt.wantErr == nil && (err != nil || data == nil):
+				t.Errorf("withRetry() = %v, %v; want data", data, err)
+			case errors.As(tt.wantErr, &wantSE) && (!errors.As(err, &se) || se.StatusCode != wantSE.StatusCode):
+				t.Errorf("withRetry() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRetriesUpstreamErrors(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, `{"title":"Inception","releaseDate":"2010-07-16","revenue":{"worldwide":836800000}}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "key")
+	c.RetryBase, c.RetryMax = time.Millisecond, time.Millisecond
+	data, err := c.FetchFull(context.Background(), "Inception")
+	if err != nil {
+		t.Fatalf("FetchFull() error = %v", err)
+	}
+	if data.BoxOffice == nil || data.BoxOffice.Revenue.Worldwide != 836800000 || data.BoxOffice.Currency != "USD" {
+		t.Errorf("FetchFull() box office = %+v", data.BoxOffice)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
@@ -2,15 +2,125 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"interview/internal/reconcile"
 )
 
 type Config struct {
-	Port            string
-	AuthToken       string
-	DBURL           string
-	BoxofficeURL    string
-	BoxofficeAPIKey string
+	Port      string
+	AuthToken string
+	// api_tokens.last_used_at的更新间隔，距上次记录不到这么久的请求不再写库
+	TokenLastUsedInterval time.Duration
+	DBURL                 string
+	BoxofficeURL          string
+	BoxofficeAPIKey       string
+
+	// 多个票房数据源（BOXOFFICE_PROVIDERS，按优先级排列），为空时只用BOXOFFICE_URL/BOXOFFICE_API_KEY
+	BoxofficeProviders []BoxofficeProvider
+	// 多个数据源的合并方式：priority（用第一个有结果的）| merge（逐字段取优先级最高的非空值）
+	BoxofficeMerge string
+
+	// 票房上游：单次请求超时和重试（总耗时受创建电影时的3s上限约束）
+	BoxofficeAttemptTimeout time.Duration
+	BoxofficeMaxRetries     int
+	BoxofficeRetryBase      time.Duration
+	BoxofficeRetryMax       time.Duration
+
+	// 熔断：窗口内请求数>=MinRequests且失败率>=FailureRate时打开，CoolDown后半开探测
+	BoxofficeBreakerEnabled     bool
+	BoxofficeBreakerWindow      time.Duration
+	BoxofficeBreakerMinRequests int
+	BoxofficeBreakerFailureRate float64
+	BoxofficeBreakerCoolDown    time.Duration
+
+	// 创建电影时上游的标题/上映日期和用户填的不一致时，直接改成上游值的字段（title、releaseDate），其余只返回warnings
+	BoxofficeAutoCorrect []string
+
+	// 票房查询缓存：off | memory（进程内LRU）| postgres（boxoffice_cache表，多实例共享）
+	BoxofficeCache            string
+	BoxofficeCacheTTL         time.Duration
+	BoxofficeCacheNegativeTTL time.Duration
+	BoxofficeCacheSize        int
+	// 并发查询合并成一次上游请求，这次请求不跟随调用方取消，用这个超时
+	BoxofficeCacheFetchTimeout time.Duration
+
+	// 汇率表（CSV：date,currency,rate），为空时用内置的exchange-rates.csv
+	ExchangeRatesFile string
+
+	// 异步票房补全：开启后创建电影只入队，由worker补全票房数据
+	BoxofficeAsync         bool
+	EnrichmentWorkers      int
+	EnrichmentPollInterval time.Duration
+	EnrichmentMaxAttempts  int
+	EnrichmentBackoff      time.Duration
+	EnrichmentLease        time.Duration
+	// 单个任务查上游和写库的超时，必须小于EnrichmentLease，否则任务还在跑就会被别的worker重新领取
+	EnrichmentJobTimeout time.Duration
+
+	// 定时刷新已有电影的票房数据
+	RefreshEnabled   bool
+	RefreshInterval  time.Duration
+	RefreshMaxAge    time.Duration
+	RefreshBatchSize int
+	// 刷新时单部电影的上游调用超时（定时刷新和手动刷新都用）
+	RefreshRequestTimeout time.Duration
+
+	// rater身份：header（直接信任X-Rater-Id）| hmac | jwt
+	RaterAuthMode    string
+	RaterTokenSecret string
+
+	// JWT/OIDC：配置了JWKS（文件路径或URL）才启用
+	JWTJWKS        string
+	JWTIssuer      string
+	JWTAudience    string
+	JWTRolesClaim  string
+	JWTScopesClaim string
+	JWTRaterClaim  string
+
+	// 刷分检测
+	AnomalyEnabled          bool
+	AnomalyWindow           time.Duration
+	AnomalyBaselineWindow   time.Duration
+	AnomalyMinVolume        int
+	AnomalyVolumeFactor     float64
+	AnomalyShiftThreshold   float64
+	AnomalyExtremeShare     float64
+	AnomalyFreshRaterAge    time.Duration
+	RatingExcludeSuspicious bool
+
+	// 限流（令牌桶，每秒速率 + 突发容量）
+	RateLimitEnabled     bool
+	RateLimitReadRPS     float64
+	RateLimitReadBurst   int
+	RateLimitWriteRPS    float64
+	RateLimitWriteBurst  int
+	RateLimitRatingRPS   float64
+	RateLimitRatingBurst int
+
+	// 信任的反向代理（IP或CIDR，逗号分隔）。只有来自这些地址的请求才采信X-Forwarded-For/X-Real-IP，
+	// 默认不信任任何代理，客户端IP就是连接的对端地址（限流、审计日志都用它）
+	TrustedProxies []string
+
+	// OpenAPI契约校验：off | log（只记录不一致）| reject（请求返回400，响应返回500）
+	OpenAPIValidation string
+	// 是否同时校验响应（需要缓冲整个响应体，只建议在dev/test开启）
+	OpenAPIValidateResponses bool
+
+	// 请求校验：允许的genre词表（大小写不敏感，保存时规范成词表里的写法）
+	MovieGenres []string
+}
+
+// DefaultGenres 默认genre词表
+var DefaultGenres = []string{
+	"Action", "Adventure", "Animation", "Biography", "Comedy", "Crime", "Documentary",
+	"Drama", "Family", "Fantasy", "History", "Horror", "Music", "Musical", "Mystery",
+	"Romance", "Sci-Fi", "Sport", "Thriller", "War", "Western",
 }
 
 func getEnv(key, def string) string {
@@ -29,12 +139,265 @@ func mustEnv(key string) string {
 	return v
 }
 
+func getEnvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid bool env var %s: %v", key, err)
+	}
+	return b
+}
+
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid int env var %s: %v", key, err)
+	}
+	return i
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("invalid float env var %s: %v", key, err)
+	}
+	return f
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid duration env var %s: %v", key, err)
+	}
+	return d
+}
+
+// envName 数据源名字转成环境变量的一部分：大写，非字母数字换成下划线
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// getEnvList 逗号分隔的列表，空项会被忽略
+func getEnvList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// BoxofficeProvider 一个票房数据源，配置来自 BOXOFFICE_<NAME>_KIND/_URL/_API_KEY
+type BoxofficeProvider struct {
+	Name   string
+	Kind   string
+	URL    string
+	APIKey string
+}
+
 func Load() Config {
-	return Config{
-		Port:            getEnv("PORT", "8080"),
-		AuthToken:       mustEnv("AUTH_TOKEN"),
-		DBURL:           mustEnv("DB_URL"),
-		BoxofficeURL:    mustEnv("BOXOFFICE_URL"),
-		BoxofficeAPIKey: mustEnv("BOXOFFICE_API_KEY"),
+	cfg := Config{
+		Port:                  getEnv("PORT", "8080"),
+		AuthToken:             mustEnv("AUTH_TOKEN"),
+		TokenLastUsedInterval: getEnvDuration("TOKEN_LAST_USED_INTERVAL", time.Minute),
+		DBURL:                 mustEnv("DB_URL"),
+		BoxofficeURL:          getEnv("BOXOFFICE_URL", ""),
+		BoxofficeAPIKey:       getEnv("BOXOFFICE_API_KEY", ""),
+		BoxofficeMerge:        getEnv("BOXOFFICE_MERGE", "priority"),
+
+		BoxofficeAttemptTimeout: getEnvDuration("BOXOFFICE_ATTEMPT_TIMEOUT", time.Second),
+		BoxofficeMaxRetries:     getEnvInt("BOXOFFICE_MAX_RETRIES", 2),
+		BoxofficeRetryBase:      getEnvDuration("BOXOFFICE_RETRY_BASE", 100*time.Millisecond),
+		BoxofficeRetryMax:       getEnvDuration("BOXOFFICE_RETRY_MAX", time.Second),
+
+		BoxofficeBreakerEnabled:     getEnvBool("BOXOFFICE_BREAKER_ENABLED", true),
+		BoxofficeBreakerWindow:      getEnvDuration("BOXOFFICE_BREAKER_WINDOW", 30*time.Second),
+		BoxofficeBreakerMinRequests: getEnvInt("BOXOFFICE_BREAKER_MIN_REQUESTS", 5),
+		BoxofficeBreakerFailureRate: getEnvFloat("BOXOFFICE_BREAKER_FAILURE_RATE", 0.5),
+		BoxofficeBreakerCoolDown:    getEnvDuration("BOXOFFICE_BREAKER_COOLDOWN", 30*time.Second),
+
+		BoxofficeAutoCorrect: getEnvList("BOXOFFICE_AUTOCORRECT", nil),
+
+		BoxofficeCache:             getEnv("BOXOFFICE_CACHE", "memory"),
+		BoxofficeCacheTTL:          getEnvDuration("BOXOFFICE_CACHE_TTL", 10*time.Minute),
+		BoxofficeCacheNegativeTTL:  getEnvDuration("BOXOFFICE_CACHE_NEGATIVE_TTL", time.Minute),
+		BoxofficeCacheSize:         getEnvInt("BOXOFFICE_CACHE_SIZE", 1000),
+		BoxofficeCacheFetchTimeout: getEnvDuration("BOXOFFICE_CACHE_FETCH_TIMEOUT", 10*time.Second),
+
+		ExchangeRatesFile: getEnv("EXCHANGE_RATES_FILE", ""),
+
+		BoxofficeAsync:         getEnvBool("BOXOFFICE_ASYNC", false),
+		EnrichmentWorkers:      getEnvInt("ENRICHMENT_WORKERS", 2),
+		EnrichmentPollInterval: getEnvDuration("ENRICHMENT_POLL_INTERVAL", time.Second),
+		EnrichmentMaxAttempts:  getEnvInt("ENRICHMENT_MAX_ATTEMPTS", 5),
+		EnrichmentBackoff:      getEnvDuration("ENRICHMENT_BACKOFF", 5*time.Second),
+		EnrichmentLease:        getEnvDuration("ENRICHMENT_LEASE", time.Minute),
+		EnrichmentJobTimeout:   getEnvDuration("ENRICHMENT_JOB_TIMEOUT", 10*time.Second),
+
+		RefreshEnabled:        getEnvBool("REFRESH_ENABLED", false),
+		RefreshInterval:       getEnvDuration("REFRESH_INTERVAL", time.Hour),
+		RefreshMaxAge:         getEnvDuration("REFRESH_MAX_AGE", 24*time.Hour),
+		RefreshBatchSize:      getEnvInt("REFRESH_BATCH_SIZE", 50),
+		RefreshRequestTimeout: getEnvDuration("REFRESH_REQUEST_TIMEOUT", 10*time.Second),
+
+		RaterAuthMode:    getEnv("RATER_AUTH_MODE", "header"),
+		RaterTokenSecret: getEnv("RATER_TOKEN_SECRET", ""),
+
+		JWTJWKS:        getEnv("JWT_JWKS", ""),
+		JWTIssuer:      getEnv("JWT_ISSUER", ""),
+		JWTAudience:    getEnv("JWT_AUDIENCE", ""),
+		JWTRolesClaim:  getEnv("JWT_ROLES_CLAIM", "roles"),
+		JWTScopesClaim: getEnv("JWT_SCOPES_CLAIM", "scope"),
+		JWTRaterClaim:  getEnv("JWT_RATER_CLAIM", "sub"),
+
+		AnomalyEnabled:          getEnvBool("ANOMALY_ENABLED", true),
+		AnomalyWindow:           getEnvDuration("ANOMALY_WINDOW", time.Hour),
+		AnomalyBaselineWindow:   getEnvDuration("ANOMALY_BASELINE_WINDOW", 7*24*time.Hour),
+		AnomalyMinVolume:        getEnvInt("ANOMALY_MIN_VOLUME", 20),
+		AnomalyVolumeFactor:     getEnvFloat("ANOMALY_VOLUME_FACTOR", 5),
+		AnomalyShiftThreshold:   getEnvFloat("ANOMALY_SHIFT_THRESHOLD", 1.5),
+		AnomalyExtremeShare:     getEnvFloat("ANOMALY_EXTREME_SHARE", 0.8),
+		AnomalyFreshRaterAge:    getEnvDuration("ANOMALY_FRESH_RATER_AGE", 24*time.Hour),
+		RatingExcludeSuspicious: getEnvBool("RATING_EXCLUDE_SUSPICIOUS", false),
+
+		RateLimitEnabled:     getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitReadRPS:     getEnvFloat("RATE_LIMIT_READ_RPS", 20),
+		RateLimitReadBurst:   getEnvInt("RATE_LIMIT_READ_BURST", 40),
+		RateLimitWriteRPS:    getEnvFloat("RATE_LIMIT_WRITE_RPS", 5),
+		RateLimitWriteBurst:  getEnvInt("RATE_LIMIT_WRITE_BURST", 20),
+		RateLimitRatingRPS:   getEnvFloat("RATE_LIMIT_RATING_RPS", 1),
+		RateLimitRatingBurst: getEnvInt("RATE_LIMIT_RATING_BURST", 10),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", nil),
+
+		OpenAPIValidation:        getEnv("OPENAPI_VALIDATION", "off"),
+		OpenAPIValidateResponses: getEnvBool("OPENAPI_VALIDATE_RESPONSES", false),
+
+		MovieGenres: getEnvList("MOVIE_GENRES", DefaultGenres),
+	}
+
+	switch cfg.RaterAuthMode {
+	case "header":
+	case "hmac":
+		if cfg.RaterTokenSecret == "" {
+			log.Fatalf("RATER_TOKEN_SECRET is required when RATER_AUTH_MODE=%s", cfg.RaterAuthMode)
+		}
+	case "jwt":
+		// 有JWKS时用JWKS验签，否则用RATER_TOKEN_SECRET做HS256
+		if cfg.RaterTokenSecret == "" && cfg.JWTJWKS == "" {
+			log.Fatalf("RATER_TOKEN_SECRET or JWT_JWKS is required when RATER_AUTH_MODE=%s", cfg.RaterAuthMode)
+		}
+	default:
+		log.Fatalf("invalid RATER_AUTH_MODE: %s", cfg.RaterAuthMode)
+	}
+
+	for _, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				log.Fatalf("invalid TRUSTED_PROXIES entry: %s", proxy)
+			}
+		}
 	}
+
+	switch cfg.OpenAPIValidation {
+	case "off", "log", "reject":
+	default:
+		log.Fatalf("invalid OPENAPI_VALIDATION: %s", cfg.OpenAPIValidation)
+	}
+
+	// 没配置多个数据源时沿用原来的单一上游
+	for _, name := range getEnvList("BOXOFFICE_PROVIDERS", nil) {
+		prefix := "BOXOFFICE_" + envName(name) + "_"
+		cfg.BoxofficeProviders = append(cfg.BoxofficeProviders, BoxofficeProvider{
+			Name:   name,
+			Kind:   getEnv(prefix+"KIND", "example"),
+			URL:    mustEnv(prefix + "URL"),
+			APIKey: getEnv(prefix+"API_KEY", ""),
+		})
+	}
+	if len(cfg.BoxofficeProviders) == 0 {
+		cfg.BoxofficeURL = mustEnv("BOXOFFICE_URL")
+		cfg.BoxofficeAPIKey = mustEnv("BOXOFFICE_API_KEY")
+	}
+	switch cfg.BoxofficeMerge {
+	case "priority", "merge":
+	default:
+		log.Fatalf("invalid BOXOFFICE_MERGE: %s", cfg.BoxofficeMerge)
+	}
+
+	for _, field := range cfg.BoxofficeAutoCorrect {
+		if !slices.Contains(reconcile.Fields, field) {
+			log.Fatalf("invalid BOXOFFICE_AUTOCORRECT field: %s (allowed: %s)", field, strings.Join(reconcile.Fields, ", "))
+		}
+	}
+
+	switch cfg.BoxofficeCache {
+	case "off", "memory", "postgres":
+	default:
+		log.Fatalf("invalid BOXOFFICE_CACHE: %s", cfg.BoxofficeCache)
+	}
+	if cfg.BoxofficeCache != "off" && (cfg.BoxofficeCacheTTL < 0 || cfg.BoxofficeCacheNegativeTTL < 0) {
+		log.Fatalf("BOXOFFICE_CACHE_TTL and BOXOFFICE_CACHE_NEGATIVE_TTL must not be negative")
+	}
+	if cfg.BoxofficeCache != "off" && cfg.BoxofficeCacheFetchTimeout <= 0 {
+		log.Fatalf("BOXOFFICE_CACHE_FETCH_TIMEOUT must be positive")
+	}
+	if cfg.BoxofficeCache == "memory" && cfg.BoxofficeCacheSize <= 0 {
+		log.Fatalf("BOXOFFICE_CACHE_SIZE must be positive when BOXOFFICE_CACHE=memory")
+	}
+
+	// 检测按窗口长度折算基线，为0时会除出Inf/NaN
+	if cfg.AnomalyWindow <= 0 || cfg.AnomalyBaselineWindow <= 0 {
+		log.Fatalf("ANOMALY_WINDOW and ANOMALY_BASELINE_WINDOW must be positive")
+	}
+	if cfg.AnomalyMinVolume < 0 || cfg.AnomalyVolumeFactor < 0 || cfg.AnomalyShiftThreshold < 0 || cfg.AnomalyFreshRaterAge < 0 {
+		log.Fatalf("ANOMALY_MIN_VOLUME, ANOMALY_VOLUME_FACTOR, ANOMALY_SHIFT_THRESHOLD and ANOMALY_FRESH_RATER_AGE must not be negative")
+	}
+	if cfg.AnomalyExtremeShare <= 0 || cfg.AnomalyExtremeShare > 1 {
+		log.Fatalf("ANOMALY_EXTREME_SHARE must be in (0, 1]")
+	}
+	if cfg.TokenLastUsedInterval < 0 {
+		log.Fatalf("TOKEN_LAST_USED_INTERVAL must not be negative")
+	}
+
+	if cfg.BoxofficeAsync && (cfg.EnrichmentJobTimeout <= 0 || cfg.EnrichmentLease <= cfg.EnrichmentJobTimeout) {
+		log.Fatalf("ENRICHMENT_LEASE (%s) must be longer than ENRICHMENT_JOB_TIMEOUT (%s)", cfg.EnrichmentLease, cfg.EnrichmentJobTimeout)
+	}
+
+	if cfg.RefreshEnabled && (cfg.RefreshInterval <= 0 || cfg.RefreshBatchSize <= 0) {
+		log.Fatalf("REFRESH_INTERVAL and REFRESH_BATCH_SIZE must be positive when REFRESH_ENABLED=true")
+	}
+	if cfg.RefreshRequestTimeout <= 0 {
+		log.Fatalf("REFRESH_REQUEST_TIMEOUT must be positive")
+	}
+
+	return cfg
 }
@@ -0,0 +1,150 @@
+package currency
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Base 汇率表的基准币种，rate都是1 USD兑换多少该币种
+const Base = "USD"
+
+// ErrUnknownCurrency 汇率表里没有这个币种
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// ErrNoRate 日期早于汇率表里这个币种的第一条汇率
+var ErrNoRate = errors.New("no exchange rate for date")
+
+// Rate 从Date当天起生效的汇率，到同币种的下一条为止
+type Rate struct {
+	Date     time.Time
+	Currency string
+	PerBase  float64
+}
+
+// Quote 一次换算用到的汇率：1单位原币种兑换Rate单位目标币种，Date是汇率生效的日期
+type Quote struct {
+	From string
+	To   string
+	Rate float64
+	Date time.Time
+}
+
+// Table 按币种、日期排好序的汇率表
+type Table struct {
+	rates map[string][]Rate
+}
+
+// Load 从CSV文件读取汇率表（date,currency,rate，#开头的行是注释）
+func Load(path string) (*Table, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read exchange rates: %w", err)
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+// Parse 解析CSV格式的汇率表，第一行可以是表头
+func Parse(r io.Reader) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+
+	t := &Table{rates: map[string][]Rate{}}
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse exchange rates: %w", err)
+		}
+		if line == 1 && strings.EqualFold(rec[0], "date") {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("parse exchange rates: invalid date %q", rec[0])
+		}
+		code := strings.ToUpper(strings.TrimSpace(rec[1]))
+		rate, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("parse exchange rates: invalid rate %q for %s", rec[2], code)
+		}
+		t.rates[code] = append(t.rates[code], Rate{Date: date, Currency: code, PerBase: rate})
+	}
+	for _, rates := range t.rates {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].Date.Before(rates[j].Date) })
+	}
+	return t, nil
+}
+
+// Has 是否能换算这个币种
+func (t *Table) Has(code string) bool {
+	code = strings.ToUpper(code)
+	return code == Base || len(t.rates[code]) > 0
+}
+
+// Currencies 汇率表支持的币种（含USD）
+func (t *Table) Currencies() []string {
+	out := []string{Base}
+	for code := range t.rates {
+		out = append(out, code)
+	}
+	sort.Strings(out[1:])
+	return out
+}
+
+// perBase at当天生效的汇率；at早于表里第一条时没有汇率，不拿别的年份的汇率凑
+func (t *Table) perBase(code string, at time.Time) (float64, time.Time, error) {
+	if code == Base {
+		return 1, time.Time{}, nil
+	}
+	rates := t.rates[code]
+	if len(rates) == 0 {
+		return 0, time.Time{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, code)
+	}
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date.After(at) })
+	if i == 0 {
+		return 0, time.Time{}, fmt.Errorf("%w: %s on %s", ErrNoRate, code, at.Format("2006-01-02"))
+	}
+	return rates[i-1].PerBase, rates[i-1].Date, nil
+}
+
+// Quote at当天from兑to的汇率，经过USD交叉换算
+func (t *Table) Quote(from, to string, at time.Time) (Quote, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	q := Quote{From: from, To: to, Rate: 1, Date: at}
+	if from == to {
+		return q, nil
+	}
+	fromRate, fromDate, err := t.perBase(from, at)
+	if err != nil {
+		return q, err
+	}
+	toRate, toDate, err := t.perBase(to, at)
+	if err != nil {
+		return q, err
+	}
+	q.Rate = toRate / fromRate
+	// 两个币种的汇率日期不同时取较晚的一个
+	q.Date = fromDate
+	if toDate.After(q.Date) {
+		q.Date = toDate
+	}
+	return q, nil
+}
+
+// Apply 按汇率换算金额，四舍五入到整数
+func (q Quote) Apply(amount int64) int64 {
+	return int64(math.Round(float64(amount) * q.Rate))
+}
@@ -0,0 +1,127 @@
+package currency
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRates = `date,currency,rate
+# 注释行
+2010-01-01,EUR,0.75
+2015-01-01,EUR,0.90
+2010-01-01,gbp,0.64
+2012-06-01,JPY,80
+`
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestQuote(t *testing.T) {
+	table, err := Parse(strings.NewReader(testRates))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		at       string
+		wantRate float64
+		wantDate string
+		wantErr  error
+	}{
+		{"same currency", "EUR", "eur", "2000-01-01", 1, "2000-01-01", nil},
+		{"usd to eur", "USD", "EUR", "2012-03-01", 0.75, "2010-01-01", nil},
+		{"newer rate applies", "USD", "EUR", "2015-01-01", 0.90, "2015-01-01", nil},
+		{"eur to usd", "EUR", "USD", "2016-01-01", 1 / 0.90, "2015-01-01", nil},
+		// 交叉换算，日期取两个汇率中较晚的
+		{"cross rate", "GBP", "JPY", "2013-01-01", 80 / 0.64, "2012-06-01", nil},
+		{"lower case code", "usd", "gbp", "2011-01-01", 0.64, "2010-01-01", nil},
+		{"before first rate", "USD", "EUR", "2009-12-31", 0, "", ErrNoRate},
+		{"cross before first rate", "EUR", "JPY", "2011-01-01", 0, "", ErrNoRate},
+		{"unknown currency", "USD", "CHF", "2012-01-01", 0, "", ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := table.Quote(tt.from, tt.to, day(tt.at))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Quote() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(q.Rate-tt.wantRate) > 1e-9 {
+				t.Errorf("Quote() rate = %v, want %v", q.Rate, tt.wantRate)
+			}
+			if got := q.Date.Format("2006-01-02"); got != tt.wantDate {
+				t.Errorf("Quote() date = %s, want %s", got, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		rate   float64
+		amount int64
+		want   int64
+	}{
+		{1, 836800000, 836800000},
+		{0.75, 100, 75},
+		{0.755, 100, 76}, // 四舍五入
+		{80, 1000000, 80000000},
+		{1 / 0.9, 9, 10},
+	}
+	for _, tt := range tests {
+		if got := (Quote{Rate: tt.rate}).Apply(tt.amount); got != tt.want {
+			t.Errorf("Apply(%d) at %v = %d, want %d", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr bool
+	}{
+		{"without header", "2010-01-01,EUR,0.75\n", false},
+		{"empty", "", false},
+		{"bad date", "2010/01/01,EUR,0.75\n", true},
+		{"bad rate", "2010-01-01,EUR,abc\n", true},
+		{"zero rate", "2010-01-01,EUR,0\n", true},
+		{"negative rate", "2010-01-01,EUR,-1\n", true},
+		{"wrong field count", "2010-01-01,EUR\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.csv)); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasAndCurrencies(t *testing.T) {
+	table, err := Parse(strings.NewReader(testRates))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	for code, want := range map[string]bool{"USD": true, "eur": true, "GBP": true, "CHF": false} {
+		if got := table.Has(code); got != want {
+			t.Errorf("Has(%q) = %v, want %v", code, got, want)
+		}
+	}
+	if got, want := table.Currencies(), []string{"USD", "EUR", "GBP", "JPY"}; !slices.Equal(got, want) {
+		t.Errorf("Currencies() = %v, want %v", got, want)
+	}
+}
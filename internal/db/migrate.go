@@ -7,12 +7,17 @@ import (
 	"path/filepath"
 	"sort"
 
+	"interview/internal/titles"
+
 	"github.com/jmoiron/sqlx"
 )
 
 func Migrate(db *sqlx.DB) {
-	path := "internal/db/migrations"
+	MigrateDir(db, "internal/db/migrations")
+}
 
+// MigrateDir 执行指定目录下的迁移文件（测试的工作目录不是仓库根目录）
+func MigrateDir(db *sqlx.DB, path string) {
 	files, err := filepath.Glob(filepath.Join(path, "*.sql"))
 	if err != nil {
 		log.Fatalf("failed to read migrations: %v", err)
@@ -35,5 +40,29 @@ func Migrate(db *sqlx.DB) {
 		}
 	}
 
+	if err := backfillTitleKeys(db); err != nil {
+		log.Fatalf("failed to backfill title keys: %v", err)
+	}
+
 	fmt.Println("All migrations applied.")
 }
+
+// backfillTitleKeys 给013之前创建的电影补上title_key，只处理还是NULL的行，重复执行没有影响
+func backfillTitleKeys(db *sqlx.DB) error {
+	var movies []struct {
+		ID    int    `db:"id"`
+		Title string `db:"title"`
+	}
+	if err := db.Select(&movies, "SELECT id, title FROM movies WHERE title_key IS NULL"); err != nil {
+		return err
+	}
+	for _, m := range movies {
+		if _, err := db.Exec("UPDATE movies SET title_key = $1 WHERE id = $2 AND title_key IS NULL", titles.Key(m.Title), m.ID); err != nil {
+			return err
+		}
+	}
+	if len(movies) > 0 {
+		fmt.Printf("Backfilled title_key for %d movies\n", len(movies))
+	}
+	return nil
+}
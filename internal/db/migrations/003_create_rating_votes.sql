@@ -0,0 +1,15 @@
+--评分有用性投票表
+
+CREATE TABLE IF NOT EXISTS rating_votes(
+    movie_title TEXT NOT NULL,
+    rater_id TEXT NOT NULL,
+    voter_id TEXT NOT NULL,
+    helpful BOOLEAN NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (movie_title, rater_id, voter_id),
+    FOREIGN KEY (movie_title, rater_id) REFERENCES ratings(movie_title, rater_id) ON DELETE CASCADE,
+    CHECK (voter_id <> rater_id)
+);
@@ -0,0 +1,8 @@
+--评分刷分检测标记
+
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS suspicious BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS suspicious_reason TEXT;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_ratings_movie_updated ON ratings (movie_title, updated_at);
+CREATE INDEX IF NOT EXISTS idx_ratings_rater_created ON ratings (rater_id, created_at);
@@ -0,0 +1,12 @@
+--角色权限：token增加角色，评分增加隐藏标记
+
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS role TEXT;
+
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS hidden_by TEXT;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+
+--admin只能通过角色获得：之前直接授予了admin scope的token改成admin角色，否则这些token会失去所有权限
+UPDATE api_tokens
+SET role = 'admin', scopes = array_remove(scopes, 'admin')
+WHERE scopes @> '{admin}';
@@ -0,0 +1,20 @@
+--写操作审计日志
+
+CREATE TABLE IF NOT EXISTS audit_log(
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    token_name TEXT,
+    action TEXT NOT NULL,
+    method TEXT NOT NULL,
+    route TEXT NOT NULL,
+    target TEXT NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    diff JSONB,
+    request_id TEXT,
+    ip TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log (target, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, created_at);
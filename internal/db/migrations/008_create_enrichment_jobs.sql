@@ -0,0 +1,22 @@
+--异步票房补全：任务队列（status=dead 即死信）+ 电影表记录补全状态
+
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- worker按 run_at 取待执行任务
+CREATE INDEX IF NOT EXISTS idx_enrichment_jobs_runnable ON enrichment_jobs (run_at, id) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_enrichment_jobs_movie ON enrichment_jobs (movie_id);
+
+-- pending | done | failed，同步模式下为NULL
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS enrichment_status TEXT;
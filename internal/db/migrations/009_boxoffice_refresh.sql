@@ -0,0 +1,5 @@
+--票房定时刷新：记录最近一次成功请求上游的时间（NULL表示创建后还没刷新过）
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS boxoffice_fetched_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_movies_release_date ON movies (release_date DESC);
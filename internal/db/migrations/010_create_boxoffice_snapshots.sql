@@ -0,0 +1,42 @@
+--票房历史：每次成功拉到票房数据追加一条快照，电影的boxOffice取最新一条
+
+CREATE TABLE IF NOT EXISTS boxoffice_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    movie_id INT NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    revenue_worldwide BIGINT NOT NULL,
+    revenue_opening_weekend_usa BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    source TEXT NOT NULL,
+    last_updated TIMESTAMP NOT NULL,
+    fetched_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 取最新快照和按时间查历史都走这个索引
+CREATE INDEX IF NOT EXISTS idx_boxoffice_snapshots_movie ON boxoffice_snapshots (movie_id, fetched_at DESC, id DESC);
+
+-- 把movies上原来的票房字段迁成第一条快照，然后删掉这些字段（只在字段还存在时执行一次）
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'movies' AND column_name = 'boxoffice_revenue_worldwide'
+    ) THEN
+        INSERT INTO boxoffice_snapshots (movie_id, revenue_worldwide, revenue_opening_weekend_usa,
+                                         currency, source, last_updated, fetched_at)
+        SELECT id, boxoffice_revenue_worldwide, COALESCE(boxoffice_revenue_opening_weekend_usa, 0),
+               boxoffice_currency, boxoffice_source, boxoffice_last_updated,
+               COALESCE(boxoffice_fetched_at, created_at)
+        FROM movies
+        WHERE boxoffice_revenue_worldwide IS NOT NULL
+          AND boxoffice_currency IS NOT NULL
+          AND boxoffice_source IS NOT NULL
+          AND boxoffice_last_updated IS NOT NULL;
+
+        ALTER TABLE movies
+            DROP COLUMN boxoffice_revenue_worldwide,
+            DROP COLUMN boxoffice_revenue_opening_weekend_usa,
+            DROP COLUMN boxoffice_currency,
+            DROP COLUMN boxoffice_source,
+            DROP COLUMN boxoffice_last_updated;
+    END IF;
+END $$;
@@ -0,0 +1,5 @@
+--字段来源：distributor/budget/mpaRating 是用户填的还是哪个票房数据源补的
+--格式 {"budget": {"source": "provider", "provider": "...", "fetchedAt": "...", "updatedAt": "..."}}
+--没有记录的非空字段（加这个字段之前写入的）按用户填写处理，刷新时不会覆盖
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS provenance JSONB NOT NULL DEFAULT '{}';
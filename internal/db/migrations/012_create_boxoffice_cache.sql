@@ -0,0 +1,13 @@
+--票房查询缓存（BOXOFFICE_CACHE=postgres时使用，多个实例共享）
+--data为NULL表示上游没有这部电影（负缓存）；namespace是数据源名字，配置不同的实例互不影响
+
+CREATE TABLE IF NOT EXISTS boxoffice_cache (
+    namespace TEXT NOT NULL,
+    title TEXT NOT NULL,
+    data JSONB,
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (namespace, title)
+);
+
+CREATE INDEX IF NOT EXISTS idx_boxoffice_cache_expires_at ON boxoffice_cache (expires_at);
@@ -0,0 +1,6 @@
+--规范化的标题（见internal/titles.Key：忽略大小写、重音、标点、开头的冠词和末尾的年份），查重和搜索用
+
+--已有的电影由迁移执行完后的Go代码回填（db.backfillTitleKeys），规则要和titles.Key完全一致，SQL里做不到（NFKD、全角）
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS title_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_movies_title_key ON movies (title_key);
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FieldChange 审计diff中单个字段的变化
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEvent 一条审计记录
+type AuditEvent struct {
+	ID        int64                  `json:"id"`
+	Actor     string                 `json:"actor"`
+	TokenName *string                `json:"tokenName"`
+	Action    string                 `json:"action"`
+	Method    string                 `json:"method"`
+	Route     string                 `json:"route"`
+	Target    string                 `json:"target"`
+	Before    json.RawMessage        `json:"before"`
+	After     json.RawMessage        `json:"after"`
+	Diff      map[string]FieldChange `json:"diff"`
+	RequestID *string                `json:"requestId"`
+	IP        *string                `json:"ip"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
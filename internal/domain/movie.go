@@ -14,6 +14,35 @@ type BoxOffice struct {
 	Currency    string           `json:"currency"`
 	Source      string           `json:"source"`
 	LastUpdated time.Time        `json:"lastUpdated"`
+	// Original 请求了 ?currency= 时换算前的金额和汇率，不换算时不返回
+	Original *BoxOfficeOriginal `json:"original,omitempty"`
+	// Unconverted 请求了 ?currency= 但没有对应的汇率（币种或日期不在汇率表里），金额仍是Currency的原值
+	Unconverted bool `json:"unconverted,omitempty"`
+}
+
+// BoxOfficeOriginal 换算前的票房金额（上游给的币种），Rate是1单位原币种兑换多少目标币种
+type BoxOfficeOriginal struct {
+	Revenue  BoxOfficeRevenue `json:"revenue"`
+	Currency string           `json:"currency"`
+	Rate     float64          `json:"rate"`
+	RateDate string           `json:"rateDate"`
+}
+
+// BudgetCurrency 预算统一按美元保存
+const BudgetCurrency = "USD"
+
+// BudgetOriginal 换算前的预算
+type BudgetOriginal struct {
+	Amount   int64   `json:"amount"`
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+	RateDate string  `json:"rateDate"`
+}
+
+// BoxOfficeSnapshot 票房历史中的一条记录，FetchedAt为从上游拉取的时间
+type BoxOfficeSnapshot struct {
+	BoxOffice
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 // Movie entity 返回到客户端
@@ -26,4 +55,40 @@ type Movie struct {
 	Budget      *int64     `json:"budget"`      // 可为空，但始终返回（即使为null）
 	MpaRating   *string    `json:"mpaRating"`   // 可为空，但始终返回（即使为null）
 	BoxOffice   *BoxOffice `json:"boxOffice"`   // 可为空，但始终返回（即使为null）
+	// BudgetOriginal 请求了 ?currency= 时换算前的预算，不换算时不返回
+	BudgetOriginal *BudgetOriginal `json:"budgetOriginal,omitempty"`
+	// BudgetUnconverted 请求了 ?currency= 但没有对应的汇率，budget仍是USD
+	BudgetUnconverted bool `json:"budgetUnconverted,omitempty"`
+	// 异步补全模式下的票房补全状态：pending | done | failed，同步模式不返回
+	EnrichmentStatus *string `json:"enrichmentStatus,omitempty"`
+	// Warnings 创建时用户填的标题、上映日期和上游不一致的提示，只在创建的响应里返回
+	Warnings []MetadataWarning `json:"warnings,omitempty"`
+	// Provenance distributor/budget/mpaRating的来源，只在 ?include=provenance 时返回
+	Provenance Provenance `json:"provenance,omitempty"`
+}
+
+// 字段来源
+const (
+	SourceUser     = "user"
+	SourceProvider = "provider"
+)
+
+// FieldProvenance 一个字段值的来源：用户填写，或者某个票房数据源在FetchedAt拉取的
+type FieldProvenance struct {
+	Source    string     `json:"source"`
+	Provider  string     `json:"provider,omitempty"`
+	FetchedAt *time.Time `json:"fetchedAt,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Provenance 字段名（JSON名，如mpaRating）到来源的映射，值为null的字段没有记录
+type Provenance map[string]FieldProvenance
+
+// MetadataWarning 用户填的值和上游不一致；Corrected为true表示已经按上游的值改正
+type MetadataWarning struct {
+	Field         string `json:"field"`
+	Message       string `json:"message"`
+	UserValue     string `json:"userValue"`
+	UpstreamValue string `json:"upstreamValue"`
+	Corrected     bool   `json:"corrected"`
 }
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // RatingSubmit 评分提交请求
 type RatingSubmit struct {
 	Rating float64 `json:"rating" binding:"required"`
@@ -17,3 +19,26 @@ type RatingAggregate struct {
 	Average float64 `json:"average"`
 	Count   int     `json:"count"`
 }
+
+// Review 单条评分记录（含有用性投票统计）
+type Review struct {
+	MovieTitle     string    `json:"movieTitle"`
+	RaterID        string    `json:"raterId"`
+	Rating         float64   `json:"rating"`
+	HelpfulCount   int       `json:"helpfulCount"`
+	UnhelpfulCount int       `json:"unhelpfulCount"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// VoteSubmit 有用性投票请求
+type VoteSubmit struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+// VoteResult 投票结果
+type VoteResult struct {
+	MovieTitle string `json:"movieTitle"`
+	RaterID    string `json:"raterId"`
+	VoterID    string `json:"voterId"`
+	Helpful    bool   `json:"helpful"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// APIToken API token信息（不含明文和哈希）
+type APIToken struct {
+	Name       string     `json:"name"`
+	Role       *string    `json:"role"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// APITokenCreate 创建token请求
+type APITokenCreate struct {
+	Name      string     `json:"name" binding:"required"`
+	Role      *string    `json:"role"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// APITokenCreated 创建结果，Token明文只返回这一次
+type APITokenCreated struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,315 @@
+package enrichment
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"interview/internal/audit"
+	"interview/internal/boxoffice"
+	"interview/internal/config"
+	"interview/internal/domain"
+	"interview/internal/provenance"
+	"interview/internal/reconcile"
+	"interview/internal/snapshots"
+	"interview/internal/titles"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// movies.enrichment_status 的取值
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Queue 基于Postgres的票房补全任务队列：多个worker用 FOR UPDATE SKIP LOCKED 抢任务，
+// 失败按指数退避重新排队，超过MaxAttempts进入死信（status=dead）
+type Queue struct {
+	DB        *sqlx.DB
+	BoxOffice boxoffice.Provider
+	Audit     *audit.Logger
+
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	Backoff      time.Duration // 第n次失败后等待 Backoff*2^(n-1)，见backoff
+	Lease        time.Duration // running超过这个时间视为worker已挂，任务可被重新领取
+	JobTimeout   time.Duration
+	// AutoCorrect 和同步创建一样按上游改正的字段（BOXOFFICE_AUTOCORRECT），见reconcile.Check
+	AutoCorrect map[string]bool
+}
+
+type job struct {
+	ID          int64  `db:"id"`
+	MovieID     int    `db:"movie_id"`
+	Title       string `db:"title"`
+	Attempts    int    `db:"attempts"`
+	MaxAttempts int    `db:"max_attempts"`
+	// ReleaseYear 电影的上映年份，查上游时区分同名的翻拍
+	ReleaseYear int `db:"release_year"`
+}
+
+// SystemActor worker没有请求上下文，改动电影时审计的操作者记为system
+var SystemActor = audit.Event{
+	Actor:  "system:enrichment",
+	Method: "WORKER",
+	Route:  "enrichment",
+}
+
+// movieFields 补全会改动的电影字段，json名就是审计diff里的字段名
+type movieFields struct {
+	Title       string  `json:"title"`
+	ReleaseDate string  `json:"releaseDate"`
+	Distributor *string `json:"distributor"`
+	Budget      *int64  `json:"budget"`
+	MpaRating   *string `json:"mpaRating"`
+}
+
+func New(db *sqlx.DB, provider boxoffice.Provider, auditLogger *audit.Logger, cfg config.Config) *Queue {
+	return &Queue{
+		DB:           db,
+		BoxOffice:    provider,
+		Audit:        auditLogger,
+		Workers:      cfg.EnrichmentWorkers,
+		PollInterval: cfg.EnrichmentPollInterval,
+		MaxAttempts:  cfg.EnrichmentMaxAttempts,
+		Backoff:      cfg.EnrichmentBackoff,
+		Lease:        cfg.EnrichmentLease,
+		JobTimeout:   cfg.EnrichmentJobTimeout,
+		AutoCorrect:  reconcile.Set(cfg.BoxofficeAutoCorrect),
+	}
+}
+
+// Enqueue 创建补全任务；传入事务，和插入电影一起提交
+func (q *Queue) Enqueue(ctx context.Context, tx sqlx.ExecerContext, movieID int, title string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO enrichment_jobs (movie_id, title, max_attempts)
+		VALUES ($1, $2, $3)
+	`, movieID, title, q.MaxAttempts)
+	if err != nil {
+		return fmt.Errorf("enqueue enrichment: %w", err)
+	}
+	return nil
+}
+
+// Start 启动worker，ctx取消后返回的WaitGroup会在所有worker退出后完成
+func (q *Queue) Start(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < q.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.run(ctx)
+		}()
+	}
+	log.Printf("enrichment: started %d workers", q.Workers)
+	return &wg
+}
+
+func (q *Queue) run(ctx context.Context) {
+	ticker := time.NewTicker(q.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 有任务就连续处理，队列空了再等下一个tick
+		for ctx.Err() == nil {
+			ok, err := q.ProcessOne(ctx)
+			if err != nil {
+				log.Printf("enrichment: %v", err)
+				break
+			}
+			if !ok {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOne 领取并执行一个任务，没有可执行的任务时返回false
+func (q *Queue) ProcessOne(ctx context.Context) (bool, error) {
+	var j job
+	err := q.DB.QueryRowxContext(ctx, `
+		UPDATE enrichment_jobs
+		SET status = 'running', attempts = attempts + 1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM enrichment_jobs
+			WHERE (status = 'pending' AND run_at <= NOW())
+			   OR (status = 'running' AND locked_at < NOW() - $1::float8 * INTERVAL '1 second')
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, movie_id, title, attempts, max_attempts,
+		          COALESCE((SELECT EXTRACT(YEAR FROM m.release_date)::int FROM movies m WHERE m.id = movie_id), 0) AS release_year
+	`, q.Lease.Seconds()).StructScan(&j)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim job: %w", err)
+	}
+
+	jobCtx, cancel := context.WithTimeout(boxoffice.WithYear(ctx, j.ReleaseYear), q.JobTimeout)
+	defer cancel()
+
+	data, err := q.BoxOffice.FetchFull(jobCtx, j.Title)
+	if err == nil {
+		err = q.apply(jobCtx, j, data)
+	}
+	if err != nil {
+		return true, q.fail(ctx, j, err)
+	}
+	return true, nil
+}
+
+// apply 追加票房快照；distributor/budget/mpaRating按来源合并，用户填写的值不覆盖。改动了电影字段时写一条movie.update审计
+func (q *Queue) apply(ctx context.Context, j job, data *boxoffice.BoxOfficeData) error {
+	tx, err := q.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if data != nil {
+		// 锁住这一行，避免和同时进行的PATCH互相覆盖
+		var cur provenance.Fields
+		var prov []byte
+		var meta reconcile.Metadata
+		var releaseDate time.Time
+		err = tx.QueryRowContext(ctx, `
+			SELECT distributor, budget, mpa_rating, provenance, title, release_date FROM movies WHERE id = $1 FOR UPDATE
+		`, j.MovieID).Scan(&cur.Distributor, &cur.Budget, &cur.MpaRating, &prov, &meta.Title, &releaseDate)
+		if err != nil {
+			return fmt.Errorf("load movie: %w", err)
+		}
+
+		// 和同步创建一样按BOXOFFICE_AUTOCORRECT改正；异步模式没有响应可以带warnings，只记日志
+		meta.ReleaseDate = releaseDate.Format("2006-01-02")
+		corrected, warnings := reconcile.Check(meta, data, q.AutoCorrect)
+		if corrected.Title != meta.Title {
+			// 评分按title关联电影，已经有评分或者上游标题被别的电影占用时不改标题
+			var blocked bool
+			err = tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM movies WHERE title = $1 AND id <> $2)
+				    OR EXISTS (SELECT 1 FROM ratings WHERE movie_title = $3)
+			`, corrected.Title, j.MovieID, meta.Title).Scan(&blocked)
+			if err != nil {
+				return fmt.Errorf("check title: %w", err)
+			}
+			if blocked {
+				corrected.Title = meta.Title
+			}
+		}
+		for _, w := range warnings {
+			fixed := (w.Field == reconcile.FieldTitle && corrected.Title != meta.Title) ||
+				(w.Field == reconcile.FieldReleaseDate && corrected.ReleaseDate != meta.ReleaseDate)
+			log.Printf("enrichment: movie %d %s mismatch: user %q, upstream %q, corrected: %t",
+				j.MovieID, w.Field, w.UserValue, w.UpstreamValue, fixed)
+		}
+
+		merged, newProv := provenance.Merge(cur, provenance.Parse(prov), data, now)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE movies
+			SET distributor = $1,
+			    budget = $2,
+			    mpa_rating = $3,
+			    provenance = $4,
+			    title = $5,
+			    release_date = $6,
+			    title_key = $7
+			WHERE id = $8
+		`, merged.Distributor, merged.Budget, merged.MpaRating, provenance.JSON(newProv),
+			corrected.Title, corrected.ReleaseDate, titles.Key(corrected.Title), j.MovieID)
+		if err != nil {
+			return fmt.Errorf("update movie: %w", err)
+		}
+		if err := snapshots.Record(ctx, tx, j.MovieID, data.BoxOffice, now); err != nil {
+			return err
+		}
+
+		// 改了电影字段时和PATCH一样写movie.update审计，和修改在同一个事务里
+		before := movieFields{meta.Title, meta.ReleaseDate, cur.Distributor, cur.Budget, cur.MpaRating}
+		after := movieFields{corrected.Title, corrected.ReleaseDate, merged.Distributor, merged.Budget, merged.MpaRating}
+		if len(changes(before, after)) > 0 && q.Audit != nil {
+			e := SystemActor
+			e.Action, e.Target, e.Before, e.After = "movie.update", "movie:"+meta.Title, &before, &after
+			if err := q.Audit.Record(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 上游404也算完成，boxOffice保持null
+	if _, err := tx.ExecContext(ctx, "UPDATE movies SET enrichment_status = $1, boxoffice_fetched_at = $2 WHERE id = $3",
+		StatusDone, now, j.MovieID); err != nil {
+		return fmt.Errorf("update movie status: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE enrichment_jobs SET status = 'done', locked_at = NULL, last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, j.ID); err != nil {
+		return fmt.Errorf("complete job: %w", err)
+	}
+	return tx.Commit()
+}
+
+// fail 记录失败；还有重试次数就退避后重新排队，否则进入死信并把电影标记为failed
+func (q *Queue) fail(ctx context.Context, j job, cause error) error {
+	if j.Attempts >= j.MaxAttempts {
+		log.Printf("enrichment: job %d for %q dead after %d attempts: %v", j.ID, j.Title, j.Attempts, cause)
+		tx, err := q.DB.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE enrichment_jobs SET status = 'dead', locked_at = NULL, last_error = $1, updated_at = NOW()
+			WHERE id = $2
+		`, cause.Error(), j.ID); err != nil {
+			return fmt.Errorf("dead-letter job: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE movies SET enrichment_status = $1 WHERE id = $2", StatusFailed, j.MovieID); err != nil {
+			return fmt.Errorf("update movie status: %w", err)
+		}
+		return tx.Commit()
+	}
+
+	delay := q.backoff(j.Attempts)
+	log.Printf("enrichment: job %d for %q failed (attempt %d/%d): %v, retrying in %s",
+		j.ID, j.Title, j.Attempts, j.MaxAttempts, cause, delay)
+	_, err := q.DB.ExecContext(ctx, `
+		UPDATE enrichment_jobs
+		SET status = 'pending', locked_at = NULL, last_error = $1,
+		    run_at = NOW() + $2::float8 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = $3
+	`, cause.Error(), delay.Seconds(), j.ID)
+	if err != nil {
+		return fmt.Errorf("reschedule job: %w", err)
+	}
+	return nil
+}
+
+// backoff 第attempts次失败后等待 Backoff*2^(attempts-1)
+func (q *Queue) backoff(attempts int) time.Duration {
+	return q.Backoff << (attempts - 1)
+}
+
+// changes 补全前后有变化的电影字段
+func changes(before, after movieFields) map[string]domain.FieldChange {
+	b, _ := json.Marshal(before)
+	a, _ := json.Marshal(after)
+	return audit.Diff(b, a)
+}
@@ -0,0 +1,65 @@
+package enrichment
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{Backoff: 5 * time.Second}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{5, 80 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	distributor, budget := "Warner Bros.", int64(160000000)
+	movie := movieFields{Title: "Inception", ReleaseDate: "2010-07-16"}
+
+	tests := []struct {
+		name   string
+		before movieFields
+		after  movieFields
+		want   []string // 有变化的字段，按字母排序
+	}{
+		{"unchanged", movie, movie, nil},
+		{
+			name:   "provider fields filled in",
+			before: movie,
+			after:  movieFields{Title: "Inception", ReleaseDate: "2010-07-16", Distributor: &distributor, Budget: &budget},
+			want:   []string{"budget", "distributor"},
+		},
+		{
+			// 按BOXOFFICE_AUTOCORRECT改正的标题和上映日期也要进审计
+			name:   "autocorrected title and release date",
+			before: movieFields{Title: "inception", ReleaseDate: "2010-07-01"},
+			after:  movie,
+			want:   []string{"releaseDate", "title"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for field := range changes(tt.before, tt.after) {
+				got = append(got, field)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changes() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
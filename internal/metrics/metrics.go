@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"interview/internal/boxoffice"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry 服务自己的指标，不用全局DefaultRegisterer，避免测试里重复注册
+type Registry struct {
+	reg *prometheus.Registry
+}
+
+func New() *Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return &Registry{reg: reg}
+}
+
+// Handler GET /metrics（Prometheus文本格式）
+func (r *Registry) Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{})
+	return gin.WrapH(h)
+}
+
+// RegisterBreaker 导出票房数据源熔断器的状态，provider标签为数据源名字
+func (r *Registry) RegisterBreaker(provider string, b *boxoffice.Breaker) {
+	labels := prometheus.Labels{"provider": provider}
+	r.reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "boxoffice_circuit_state",
+			Help:        "Box office circuit breaker state (0 closed, 1 half-open, 2 open).",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(b.Stats().State)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "boxoffice_circuit_rejected_total",
+			Help:        "Box office calls rejected while the circuit was open.",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(b.Stats().Rejected)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "boxoffice_circuit_opened_total",
+			Help:        "Times the box office circuit breaker opened.",
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(b.Stats().Transitions[boxoffice.StateOpen])
+		}),
+	)
+}
+
+// RegisterCache 导出票房缓存的命中统计
+func (r *Registry) RegisterCache(c *boxoffice.Cached) {
+	counter := func(result string, v func(boxoffice.CacheStats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "boxoffice_cache_lookups_total",
+			Help:        "Box office cache lookups by result (hit, negative_hit, miss); error counts cache backend failures.",
+			ConstLabels: prometheus.Labels{"result": result},
+		}, func() float64 {
+			return float64(v(c.Stats()))
+		})
+	}
+	r.reg.MustRegister(
+		counter("hit", func(s boxoffice.CacheStats) uint64 { return s.Hits }),
+		counter("negative_hit", func(s boxoffice.CacheStats) uint64 { return s.NegativeHits }),
+		counter("miss", func(s boxoffice.CacheStats) uint64 { return s.Misses }),
+		counter("error", func(s boxoffice.CacheStats) uint64 { return s.Errors }),
+	)
+}
@@ -0,0 +1,92 @@
+package provenance
+
+import (
+	"encoding/json"
+	"time"
+
+	"interview/internal/boxoffice"
+	"interview/internal/domain"
+)
+
+// Fields 会和上游数据合并的电影字段
+type Fields struct {
+	Distributor *string
+	Budget      *int64
+	MpaRating   *string
+}
+
+// User 记录用户填写的字段（nil的字段不记录）
+func User(prov domain.Provenance, f Fields, now time.Time) domain.Provenance {
+	out := clone(prov)
+	mark := func(field string, set bool) {
+		if set {
+			out[field] = domain.FieldProvenance{Source: domain.SourceUser, UpdatedAt: now}
+		}
+	}
+	mark(boxoffice.FieldDistributor, f.Distributor != nil)
+	mark(boxoffice.FieldBudget, f.Budget != nil)
+	mark(boxoffice.FieldMpaRating, f.MpaRating != nil)
+	return out
+}
+
+// Merge 把上游数据合并进当前值：用户填写的值永远不覆盖，来自数据源的值用新拉到的非空值替换，空字段直接补上。
+// 没有来源记录但有值的字段（记录来源之前写入的）按用户填写处理
+func Merge(cur Fields, prov domain.Provenance, data *boxoffice.BoxOfficeData, fetchedAt time.Time) (Fields, domain.Provenance) {
+	out := clone(prov)
+	if data == nil {
+		return cur, out
+	}
+
+	take := func(field string, hasCur, hasNew bool) bool {
+		if !hasNew {
+			return false
+		}
+		if p, ok := out[field]; hasCur && (!ok || p.Source == domain.SourceUser) {
+			return false
+		}
+		source := data.Sources[field]
+		if source == "" && data.BoxOffice != nil {
+			source = data.BoxOffice.Source
+		}
+		at := fetchedAt
+		out[field] = domain.FieldProvenance{Source: domain.SourceProvider, Provider: source, FetchedAt: &at, UpdatedAt: fetchedAt}
+		return true
+	}
+
+	if take(boxoffice.FieldDistributor, cur.Distributor != nil, data.Distributor != nil) {
+		cur.Distributor = data.Distributor
+	}
+	if take(boxoffice.FieldBudget, cur.Budget != nil, data.Budget != nil) {
+		cur.Budget = data.Budget
+	}
+	if take(boxoffice.FieldMpaRating, cur.MpaRating != nil, data.MpaRating != nil) {
+		cur.MpaRating = data.MpaRating
+	}
+	return cur, out
+}
+
+// Parse 读取movies.provenance（JSONB）
+func Parse(b []byte) domain.Provenance {
+	prov := domain.Provenance{}
+	if len(b) > 0 {
+		_ = json.Unmarshal(b, &prov)
+	}
+	return prov
+}
+
+// JSON 写入movies.provenance用；pq会把[]byte当bytea发送，所以返回string
+func JSON(prov domain.Provenance) string {
+	if prov == nil {
+		return "{}"
+	}
+	b, _ := json.Marshal(prov)
+	return string(b)
+}
+
+func clone(prov domain.Provenance) domain.Provenance {
+	out := make(domain.Provenance, len(prov))
+	for k, v := range prov {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package provenance
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"interview/internal/boxoffice"
+	"interview/internal/domain"
+)
+
+func TestMerge(t *testing.T) {
+	userAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetchedAt := userAt.Add(time.Hour)
+	str := func(s string) *string { return &s }
+	num := func(n int64) *int64 { return &n }
+
+	fromUser := domain.FieldProvenance{Source: domain.SourceUser, UpdatedAt: userAt}
+	fromProvider := domain.FieldProvenance{Source: domain.SourceProvider, Provider: "old", UpdatedAt: userAt}
+
+	upstream := &boxoffice.BoxOfficeData{
+		Distributor: str("Warner Bros."),
+		Budget:      num(160000000),
+		MpaRating:   str("PG-13"),
+		BoxOffice:   &domain.BoxOffice{Source: "ExampleBoxOfficeAPI"},
+		Sources:     map[string]string{boxoffice.FieldBudget: "backup"},
+	}
+
+	// want里每个字段：值（空串表示nil）和来源（空串表示没有记录）
+	type field struct{ value, source, provider string }
+	tests := []struct {
+		name        string
+		cur         Fields
+		prov        domain.Provenance
+		data        *boxoffice.BoxOfficeData
+		distributor field
+		budget      field
+		mpaRating   field
+	}{
+		{
+			name:        "fills empty fields",
+			data:        upstream,
+			distributor: field{"Warner Bros.", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+			budget:      field{"160000000", domain.SourceProvider, "backup"},
+			mpaRating:   field{"PG-13", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+		},
+		{
+			name:        "user values are kept",
+			cur:         Fields{Distributor: str("Indie"), Budget: num(1)},
+			prov:        domain.Provenance{boxoffice.FieldDistributor: fromUser, boxoffice.FieldBudget: fromUser},
+			data:        upstream,
+			distributor: field{"Indie", domain.SourceUser, ""},
+			budget:      field{"1", domain.SourceUser, ""},
+			mpaRating:   field{"PG-13", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+		},
+		{
+			// 记录来源之前写入的值按用户填写处理
+			name:        "values without provenance are kept",
+			cur:         Fields{MpaRating: str("R")},
+			data:        upstream,
+			distributor: field{"Warner Bros.", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+			budget:      field{"160000000", domain.SourceProvider, "backup"},
+			mpaRating:   field{"R", "", ""},
+		},
+		{
+			name:        "provider values are replaced",
+			cur:         Fields{Distributor: str("Old Studio")},
+			prov:        domain.Provenance{boxoffice.FieldDistributor: fromProvider},
+			data:        upstream,
+			distributor: field{"Warner Bros.", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+			budget:      field{"160000000", domain.SourceProvider, "backup"},
+			mpaRating:   field{"PG-13", domain.SourceProvider, "ExampleBoxOfficeAPI"},
+		},
+		{
+			// 上游这次没给的字段保留原来的值和来源
+			name:        "missing upstream values keep provider values",
+			cur:         Fields{Distributor: str("Old Studio")},
+			prov:        domain.Provenance{boxoffice.FieldDistributor: fromProvider},
+			data:        &boxoffice.BoxOfficeData{MpaRating: str("PG")},
+			distributor: field{"Old Studio", domain.SourceProvider, "old"},
+			mpaRating:   field{"PG", domain.SourceProvider, ""},
+		},
+		{
+			name:        "no upstream data",
+			cur:         Fields{Distributor: str("Indie")},
+			prov:        domain.Provenance{boxoffice.FieldDistributor: fromUser},
+			distributor: field{"Indie", domain.SourceUser, ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(tt.prov)
+			got, prov := Merge(tt.cur, tt.prov, tt.data, fetchedAt)
+
+			check := func(name string, value string, want field) {
+				t.Helper()
+				if value != want.value {
+					t.Errorf("%s = %q, want %q", name, value, want.value)
+				}
+				p, ok := prov[name]
+				if want.source == "" {
+					if ok {
+						t.Errorf("provenance[%s] = %+v, want none", name, p)
+					}
+					return
+				}
+				if p.Source != want.source || p.Provider != want.provider {
+					t.Errorf("provenance[%s] = %+v, want source %q provider %q", name, p, want.source, want.provider)
+				}
+				// 这次从上游取的值记录拉取时间
+				if p.Source == domain.SourceProvider && tt.prov[name] != p {
+					if p.FetchedAt == nil || !p.FetchedAt.Equal(fetchedAt) || !p.UpdatedAt.Equal(fetchedAt) {
+						t.Errorf("provenance[%s] = %+v, want fetched at %s", name, p, fetchedAt)
+					}
+				}
+			}
+			check(boxoffice.FieldDistributor, deref(got.Distributor), tt.distributor)
+			check(boxoffice.FieldBudget, derefInt(got.Budget), tt.budget)
+			check(boxoffice.FieldMpaRating, deref(got.MpaRating), tt.mpaRating)
+
+			// 不修改传入的provenance
+			if len(tt.prov) != before {
+				t.Errorf("Merge() modified the input provenance: %+v", tt.prov)
+			}
+		})
+	}
+}
+
+func TestUser(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	budget := int64(1)
+	prev := domain.Provenance{boxoffice.FieldMpaRating: {Source: domain.SourceProvider, Provider: "x"}}
+
+	prov := User(prev, Fields{Budget: &budget}, now)
+	if p := prov[boxoffice.FieldBudget]; p.Source != domain.SourceUser || !p.UpdatedAt.Equal(now) {
+		t.Errorf("provenance[budget] = %+v, want user at %s", p, now)
+	}
+	if _, ok := prov[boxoffice.FieldDistributor]; ok {
+		t.Errorf("provenance[distributor] recorded for a nil field")
+	}
+	if p := prov[boxoffice.FieldMpaRating]; p.Provider != "x" {
+		t.Errorf("provenance[mpaRating] = %+v, want the previous record kept", p)
+	}
+	if len(prev) != 1 {
+		t.Errorf("User() modified the input provenance: %+v", prev)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prov := domain.Provenance{boxoffice.FieldBudget: {Source: domain.SourceUser, UpdatedAt: now}}
+
+	if got := JSON(nil); got != "{}" {
+		t.Errorf("JSON(nil) = %s, want {}", got)
+	}
+	back := Parse([]byte(JSON(prov)))
+	if p := back[boxoffice.FieldBudget]; p.Source != domain.SourceUser || !p.UpdatedAt.Equal(now) {
+		t.Errorf("Parse(JSON()) = %+v, want %+v", back, prov)
+	}
+	if got := Parse(nil); got == nil || len(got) != 0 {
+		t.Errorf("Parse(nil) = %v, want empty map", got)
+	}
+	if got := Parse([]byte("not json")); got == nil {
+		t.Errorf("Parse(invalid) = nil, want empty map")
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
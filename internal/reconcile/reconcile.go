@@ -0,0 +1,71 @@
+package reconcile
+
+import (
+	"fmt"
+
+	"interview/internal/boxoffice"
+	"interview/internal/domain"
+)
+
+// 会和上游对比的字段（JSON名），也是BOXOFFICE_AUTOCORRECT的可选值
+const (
+	FieldTitle       = "title"
+	FieldReleaseDate = "releaseDate"
+)
+
+// Fields 可以按BOXOFFICE_AUTOCORRECT改正的字段
+var Fields = []string{FieldTitle, FieldReleaseDate}
+
+// Set 把BOXOFFICE_AUTOCORRECT的字段列表转成Check用的集合
+func Set(fields []string) map[string]bool {
+	set := map[string]bool{}
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
+
+// Metadata 用户填的标题和上映日期（YYYY-MM-DD）
+type Metadata struct {
+	Title       string
+	ReleaseDate string
+}
+
+// Check 对比用户填的值和上游的值，不一致时生成提示；autoCorrect里的字段直接改成上游的值。
+// 上游没有返回的字段不对比
+func Check(m Metadata, data *boxoffice.BoxOfficeData, autoCorrect map[string]bool) (Metadata, []domain.MetadataWarning) {
+	var warnings []domain.MetadataWarning
+	if data == nil {
+		return m, warnings
+	}
+
+	if data.Title != "" && data.Title != m.Title {
+		w := domain.MetadataWarning{
+			Field:         FieldTitle,
+			Message:       fmt.Sprintf("upstream title is %q", data.Title),
+			UserValue:     m.Title,
+			UpstreamValue: data.Title,
+		}
+		if autoCorrect[FieldTitle] {
+			m.Title, w.Corrected = data.Title, true
+		}
+		warnings = append(warnings, w)
+	}
+
+	if data.ReleaseDate != nil {
+		upstream := data.ReleaseDate.Format("2006-01-02")
+		if upstream != m.ReleaseDate {
+			w := domain.MetadataWarning{
+				Field:         FieldReleaseDate,
+				Message:       "upstream release date is " + upstream,
+				UserValue:     m.ReleaseDate,
+				UpstreamValue: upstream,
+			}
+			if autoCorrect[FieldReleaseDate] {
+				m.ReleaseDate, w.Corrected = upstream, true
+			}
+			warnings = append(warnings, w)
+		}
+	}
+	return m, warnings
+}
@@ -0,0 +1,68 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"interview/internal/boxoffice"
+)
+
+func TestCheck(t *testing.T) {
+	released := time.Date(2010, 7, 16, 0, 0, 0, 0, time.UTC)
+	upstream := &boxoffice.BoxOfficeData{Title: "Inception", ReleaseDate: &released}
+	user := Metadata{Title: "inception", ReleaseDate: "2010-07-15"}
+
+	tests := []struct {
+		name        string
+		meta        Metadata
+		data        *boxoffice.BoxOfficeData
+		autoCorrect []string
+		want        Metadata
+		// 每个warning的字段和是否已改正
+		wantWarnings map[string]bool
+	}{
+		{"no upstream data", user, nil, Fields, user, map[string]bool{}},
+		{"matching", Metadata{Title: "Inception", ReleaseDate: "2010-07-16"}, upstream, nil,
+			Metadata{Title: "Inception", ReleaseDate: "2010-07-16"}, map[string]bool{}},
+		{"warn only", user, upstream, nil, user,
+			map[string]bool{FieldTitle: false, FieldReleaseDate: false}},
+		{"correct title", user, upstream, []string{FieldTitle},
+			Metadata{Title: "Inception", ReleaseDate: "2010-07-15"},
+			map[string]bool{FieldTitle: true, FieldReleaseDate: false}},
+		{"correct both", user, upstream, Fields,
+			Metadata{Title: "Inception", ReleaseDate: "2010-07-16"},
+			map[string]bool{FieldTitle: true, FieldReleaseDate: true}},
+		// 上游没给的字段不对比
+		{"upstream without fields", user, &boxoffice.BoxOfficeData{}, Fields, user, map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := Check(tt.meta, tt.data, Set(tt.autoCorrect))
+			if got != tt.want {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("Check() warnings = %+v, want fields %v", warnings, tt.wantWarnings)
+			}
+			for _, w := range warnings {
+				corrected, ok := tt.wantWarnings[w.Field]
+				if !ok || w.Corrected != corrected {
+					t.Errorf("warning %+v, want corrected = %v for %s", w, corrected, w.Field)
+				}
+				if w.UserValue == w.UpstreamValue || w.Message == "" {
+					t.Errorf("warning %+v: want differing values and a message", w)
+				}
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	set := Set([]string{FieldTitle})
+	if !set[FieldTitle] || set[FieldReleaseDate] {
+		t.Errorf("Set([title]) = %v", set)
+	}
+	if len(Set(nil)) != 0 {
+		t.Errorf("Set(nil) = %v, want empty", Set(nil))
+	}
+}
@@ -0,0 +1,307 @@
+package refresh
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"interview/internal/audit"
+	"interview/internal/boxoffice"
+	"interview/internal/config"
+	"interview/internal/domain"
+	"interview/internal/enrichment"
+	"interview/internal/provenance"
+	"interview/internal/snapshots"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrMovieNotFound 要刷新的电影不存在
+var ErrMovieNotFound = errors.New("movie not found")
+
+// advisoryLockKey 多实例部署时只让一个实例跑定时刷新
+const advisoryLockKey = 0x626f786f // "boxo"
+
+// Result 单部电影的刷新结果
+type Result struct {
+	Title string `json:"title"`
+	// Found 上游是否有这部电影（404时为false，已有数据保持不变）
+	Found   bool                          `json:"found"`
+	Changes map[string]domain.FieldChange `json:"changes"`
+}
+
+// Failure 刷新失败的电影，Error是固定的提示，具体原因只记日志
+type Failure struct {
+	Title string `json:"title"`
+	Error string `json:"error"`
+}
+
+// Report 一批电影的刷新汇总，Results只包含有变化的电影
+type Report struct {
+	Checked  int       `json:"checked"`
+	Updated  int       `json:"updated"`
+	NotFound int       `json:"notFound"`
+	Failed   int       `json:"failed"`
+	Results  []Result  `json:"results"`
+	Failures []Failure `json:"failures"`
+}
+
+// Refresher 重新拉取票房数据。每次拉到数据追加一条票房快照，distributor/budget/mpaRating按字段来源合并，不覆盖用户填写的值
+type Refresher struct {
+	DB        *sqlx.DB
+	BoxOffice boxoffice.Provider
+	Audit     *audit.Logger
+
+	Interval  time.Duration
+	MaxAge    time.Duration // boxoffice_fetched_at早于这个时间的电影需要刷新
+	BatchSize int           // 每轮最多刷新的电影数，按上映日期从新到旧
+	// RequestTimeout 单部电影的上游调用超时
+	RequestTimeout time.Duration
+}
+
+func New(db *sqlx.DB, provider boxoffice.Provider, auditLogger *audit.Logger, cfg config.Config) *Refresher {
+	return &Refresher{
+		DB:             db,
+		BoxOffice:      provider,
+		Audit:          auditLogger,
+		Interval:       cfg.RefreshInterval,
+		MaxAge:         cfg.RefreshMaxAge,
+		BatchSize:      cfg.RefreshBatchSize,
+		RequestTimeout: cfg.RefreshRequestTimeout,
+	}
+}
+
+// fields 参与比较的字段，json名就是diff里的字段名
+type fields struct {
+	Distributor *string `json:"distributor"`
+	Budget      *int64  `json:"budget"`
+	MpaRating   *string `json:"mpaRating"`
+	Worldwide   *int64  `json:"boxOffice.revenue.worldwide"`
+	OpeningUSA  *int64  `json:"boxOffice.revenue.openingWeekendUSA"`
+	Currency    *string `json:"boxOffice.currency"`
+	Source      *string `json:"boxOffice.source"`
+	// EnrichmentStatus 异步补全失败的电影刷新成功后改为done（没有未完成的补全任务时）
+	EnrichmentStatus *string `json:"enrichmentStatus"`
+}
+
+// Start 按Interval定期执行RunOnce，ctx取消后退出
+func (r *Refresher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		log.Printf("refresh: scheduler started (interval %s, max age %s, batch %d)", r.Interval, r.MaxAge, r.BatchSize)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			report, err := r.RunOnce(ctx)
+			if err != nil {
+				log.Printf("refresh: %v", err)
+				continue
+			}
+			if report != nil && report.Checked > 0 {
+				log.Printf("refresh: checked %d, updated %d, not found %d, failed %d",
+					report.Checked, report.Updated, report.NotFound, report.Failed)
+			}
+		}
+	}()
+}
+
+// RunOnce 刷新一批过期的电影；其他实例正在刷新时返回nil
+func (r *Refresher) RunOnce(ctx context.Context) (*Report, error) {
+	conn, err := r.DB.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("acquire refresh lock: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	// 从没刷新过的优先，其次上映越近的越优先（票房还在变化）
+	var titles []string
+	err = r.DB.SelectContext(ctx, &titles, `
+		SELECT title FROM movies
+		WHERE boxoffice_fetched_at IS NULL
+		   OR boxoffice_fetched_at < NOW() - $1::float8 * INTERVAL '1 second'
+		ORDER BY boxoffice_fetched_at IS NOT NULL, release_date DESC, id
+		LIMIT $2
+	`, r.MaxAge.Seconds(), r.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("select stale movies: %w", err)
+	}
+
+	return r.RefreshTitles(ctx, titles, SystemActor), nil
+}
+
+// RefreshTitles 逐个刷新，单部失败不影响其他电影；by是写审计用的操作者，见RefreshMovie
+func (r *Refresher) RefreshTitles(ctx context.Context, titles []string, by audit.Event) *Report {
+	report := &Report{Results: []Result{}, Failures: []Failure{}}
+	for _, title := range titles {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Checked++
+
+		res, err := r.RefreshMovie(ctx, title, by)
+		if err != nil {
+			report.Failed++
+			report.Failures = append(report.Failures, Failure{Title: title, Error: "box office refresh failed"})
+			log.Printf("refresh: %q: %v", title, err)
+			continue
+		}
+		if !res.Found {
+			report.NotFound++
+		}
+		if len(res.Changes) > 0 {
+			report.Updated++
+			report.Results = append(report.Results, *res)
+			log.Printf("refresh: %q updated: %s", title, changedFields(res.Changes))
+		}
+	}
+	return report
+}
+
+// SystemActor 定时刷新没有请求上下文，操作者记为system
+var SystemActor = audit.Event{
+	Actor:  "system:boxoffice-refresh",
+	Method: "SCHEDULED",
+	Route:  "boxoffice-refresh",
+}
+
+// RefreshMovie 立即刷新一部电影，返回变化的字段；上游失败时不改动数据。
+// 有变化时在同一个事务里写一条boxoffice.refresh审计，by提供操作者、路由等信息
+func (r *Refresher) RefreshMovie(ctx context.Context, title string, by audit.Event) (*Result, error) {
+	var id, year int
+	err := r.DB.QueryRowContext(ctx, "SELECT id, EXTRACT(YEAR FROM release_date)::int FROM movies WHERE title = $1", title).Scan(&id, &year)
+	if err == sql.ErrNoRows {
+		return nil, ErrMovieNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load movie: %w", err)
+	}
+
+	// 刷新要拿上游的最新数据，不读缓存；按上映年份区分同名的翻拍
+	fetchCtx, cancel := context.WithTimeout(boxoffice.WithYear(boxoffice.SkipCache(ctx), year), r.RequestTimeout)
+	defer cancel()
+	data, err := r.BoxOffice.FetchFull(fetchCtx, title)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 拉取上游期间可能有PATCH，锁住这一行后再读当前值和字段来源
+	var before fields
+	var prov []byte
+	err = tx.QueryRowContext(ctx, `
+		SELECT m.distributor, m.budget, m.mpa_rating,
+		       bo.revenue_worldwide, bo.revenue_opening_weekend_usa,
+		       bo.currency, bo.source, m.enrichment_status, m.provenance
+		FROM movies m `+snapshots.LatestJoin+`
+		WHERE m.id = $1
+		FOR UPDATE OF m
+	`, id).Scan(&before.Distributor, &before.Budget, &before.MpaRating,
+		&before.Worldwide, &before.OpeningUSA, &before.Currency, &before.Source, &before.EnrichmentStatus, &prov)
+	if err == sql.ErrNoRows {
+		return nil, ErrMovieNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load movie: %w", err)
+	}
+
+	// 还有排队或执行中的补全任务时不改enrichment_status，由worker完成任务时一起更新，避免显示done但任务还在跑
+	var jobActive bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM enrichment_jobs WHERE movie_id = $1 AND status IN ('pending', 'running'))
+	`, id).Scan(&jobActive)
+	if err != nil {
+		return nil, fmt.Errorf("check enrichment jobs: %w", err)
+	}
+
+	now := time.Now()
+	after := before
+	if after.EnrichmentStatus != nil && !jobActive {
+		done := enrichment.StatusDone
+		after.EnrichmentStatus = &done
+	}
+
+	// 用户填写的字段不覆盖，来自数据源的字段用新值
+	merged, newProv := provenance.Merge(provenance.Fields{
+		Distributor: before.Distributor,
+		Budget:      before.Budget,
+		MpaRating:   before.MpaRating,
+	}, provenance.Parse(prov), data, now)
+	after.Distributor, after.Budget, after.MpaRating = merged.Distributor, merged.Budget, merged.MpaRating
+
+	var box *domain.BoxOffice
+	if data != nil {
+		if box = data.BoxOffice; box != nil {
+			after.Worldwide, after.OpeningUSA = &box.Revenue.Worldwide, &box.Revenue.OpeningWeekendUSA
+			after.Currency, after.Source = &box.Currency, &box.Source
+		}
+	}
+
+	// 上游404时不追加快照，保留已有的票房数据，只记录这次请求的时间
+	_, err = tx.ExecContext(ctx, `
+		UPDATE movies
+		SET distributor = $1,
+		    budget = $2,
+		    mpa_rating = $3,
+		    boxoffice_fetched_at = $4,
+		    enrichment_status = $5,
+		    provenance = $6
+		WHERE id = $7
+	`, after.Distributor, after.Budget, after.MpaRating, now, after.EnrichmentStatus, provenance.JSON(newProv), id)
+	if err != nil {
+		return nil, fmt.Errorf("update movie: %w", err)
+	}
+	if err := snapshots.Record(ctx, tx, id, box, now); err != nil {
+		return nil, err
+	}
+
+	b, _ := json.Marshal(before)
+	a, _ := json.Marshal(after)
+	res := &Result{Title: title, Found: data != nil, Changes: audit.Diff(b, a)}
+	if len(res.Changes) > 0 && r.Audit != nil {
+		e := by
+		e.Action, e.Target, e.Before, e.After = "boxoffice.refresh", "movie:"+title, &before, &after
+		if err := r.Audit.Record(ctx, tx, e); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// changedFields 变化字段名，按字母排序方便看日志
+func changedFields(changes map[string]domain.FieldChange) string {
+	fields := make([]string, 0, len(changes))
+	for k := range changes {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,60 @@
+package refresh
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"interview/internal/audit"
+	"interview/internal/domain"
+)
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes map[string]domain.FieldChange
+		want    string
+	}{
+		{"none", nil, ""},
+		{"sorted", map[string]domain.FieldChange{"mpaRating": {}, "boxOffice.currency": {}, "budget": {}},
+			"boxOffice.currency, budget, mpaRating"},
+	}
+	for _, tt := range tests {
+		if got := changedFields(tt.changes); got != tt.want {
+			t.Errorf("%s: changedFields() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// diff里的字段名就是fields的json名，和openapi.yml里BoxOfficeRefreshResult的描述一致
+func TestFieldsDiff(t *testing.T) {
+	worldwide, newWorldwide := int64(100), int64(200)
+	failed, done := "failed", "done"
+	before := fields{Worldwide: &worldwide, EnrichmentStatus: &failed}
+	after := fields{Worldwide: &newWorldwide, EnrichmentStatus: &done}
+
+	b, _ := json.Marshal(before)
+	a, _ := json.Marshal(after)
+	got, _ := json.Marshal(audit.Diff(b, a))
+	want := `{"boxOffice.revenue.worldwide":{"before":100,"after":200},"enrichmentStatus":{"before":"failed","after":"done"}}`
+	if string(got) != want {
+		t.Errorf("Diff() = %s, want %s", got, want)
+	}
+}
+
+func TestRefreshTitlesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// ctx已取消时不再刷新剩下的电影，也不访问数据库
+	report := (&Refresher{}).RefreshTitles(ctx, []string{"Inception", "Avatar"}, SystemActor)
+	if report.Checked != 0 {
+		t.Errorf("Checked = %d, want 0", report.Checked)
+	}
+	// 没有结果时也返回空数组而不是null
+	got, _ := json.Marshal(report)
+	want := `{"checked":0,"updated":0,"notFound":0,"failed":0,"results":[],"failures":[]}`
+	if string(got) != want {
+		t.Errorf("report = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"interview/internal/apierror"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /admin/rating-anomalies - 刷分检测报告
+func (h *HandlerSet) RatingAnomalies(c *gin.Context) {
+	// 指定title时只分析单部电影
+	if title := c.Query("title"); title != "" {
+		// 检查电影是否存在
+		var exists bool
+		err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM movies WHERE title = $1)", title).Scan(&exists)
+		if err != nil || !exists {
+			apierror.Write(c, apierror.NotFound("Movie"))
+			return
+		}
+
+		report, err := h.Anomaly.Analyze(c.Request.Context(), title)
+		if err != nil {
+			apierror.Write(c, apierror.Internal("Failed to analyze ratings"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": []interface{}{report}})
+		return
+	}
+
+	reports, err := h.Anomaly.Reports(c.Request.Context())
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to analyze ratings"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": reports})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"interview/internal/apierror"
+	"interview/internal/audit"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// auditActor 发起请求的操作者。IP用c.ClientIP()，只采信TRUSTED_PROXIES里的代理转发的X-Forwarded-For
+func auditActor(c *gin.Context) audit.Event {
+	tokenName := c.GetString("token_name")
+	actor := tokenName
+	// 评分相关操作没有token，用rater作为操作者
+	if raterID := c.GetString("rater_id"); raterID != "" && actor == "" {
+		actor = "rater:" + raterID
+	}
+	return audit.Event{
+		Actor:     actor,
+		TokenName: tokenName,
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		RequestID: c.GetString("request_id"),
+		IP:        c.ClientIP(),
+	}
+}
+
+// recordAudit 在写操作的事务里记录审计，返回错误时调用方应回滚，不能出现有修改没审计的情况
+func (h *HandlerSet) recordAudit(c *gin.Context, tx sqlx.ExecerContext, action, target string, before, after interface{}) error {
+	e := auditActor(c)
+	e.Action, e.Target, e.Before, e.After = action, target, before, after
+	if err := h.Audit.Record(c.Request.Context(), tx, e); err != nil {
+		log.Printf("failed to record audit event %s %s: %v", action, target, err)
+		return err
+	}
+	return nil
+}
+
+// GET /admin/audit-log - 查询审计日志
+func (h *HandlerSet) ListAuditLog(c *gin.Context) {
+	f := audit.Filter{
+		Actor:     c.Query("actor"),
+		TokenName: c.Query("tokenName"),
+		Action:    c.Query("action"),
+		Target:    c.Query("target"),
+		RequestID: c.Query("requestId"),
+		Limit:     50,
+	}
+
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		f.Limit = min(l, 500)
+	}
+	if o, err := strconv.Atoi(c.Query("cursor")); err == nil && o > 0 {
+		f.Offset = o
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, name+" must be an RFC3339 timestamp"))
+			return
+		}
+		*dst = &t
+	}
+
+	// 多查一条判断是否有下一页
+	f.Limit++
+	events, err := h.Audit.Query(c.Request.Context(), f)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to query audit log"))
+		return
+	}
+	f.Limit--
+
+	var nextCursor *string
+	if len(events) > f.Limit {
+		events = events[:f.Limit]
+		next := strconv.Itoa(f.Offset + f.Limit)
+		nextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":      events,
+		"nextCursor": nextCursor,
+	})
+}
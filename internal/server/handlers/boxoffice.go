@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"interview/internal/apierror"
+	"interview/internal/snapshots"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /movies/:title/boxoffice/history - 票房快照历史，按拉取时间从旧到新（公开）
+func (h *HandlerSet) BoxOfficeHistory(c *gin.Context) {
+	title := c.Param("title")
+
+	limit, offset := 100, 0
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = min(l, 1000)
+	}
+	if o, err := strconv.Atoi(c.Query("cursor")); err == nil && o > 0 {
+		offset = o
+	}
+
+	var since, until *time.Time
+	for name, dst := range map[string]**time.Time{"since": &since, "until": &until} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, name+" must be an RFC3339 timestamp"))
+			return
+		}
+		*dst = &t
+	}
+
+	toCurrency, ok := h.targetCurrency(c)
+	if !ok {
+		return
+	}
+
+	var movieID int
+	if err := h.DB.QueryRow("SELECT id FROM movies WHERE title = $1", title).Scan(&movieID); err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(c, apierror.NotFound("Movie"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to query movie"))
+		return
+	}
+
+	// 多查一条判断是否有下一页
+	items, err := snapshots.List(c.Request.Context(), h.DB, movieID, since, until, limit+1, offset)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to query box office history"))
+		return
+	}
+
+	for i := range items {
+		h.convertBoxOffice(&items[i].BoxOffice, toCurrency)
+	}
+
+	var nextCursor *string
+	if len(items) > limit {
+		items = items[:limit]
+		next := strconv.Itoa(offset + limit)
+		nextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"movieTitle": title,
+		"items":      items,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"interview/internal/apierror"
+	"interview/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// targetCurrency 解析 ?currency=（如EUR），没传时返回""；汇率表里没有的币种返回400
+func (h *HandlerSet) targetCurrency(c *gin.Context) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	if code == "" {
+		return "", true
+	}
+	if !h.Rates.Has(code) {
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest,
+			"currency must be one of: "+strings.Join(h.Rates.Currencies(), ", ")))
+		return "", false
+	}
+	return code, true
+}
+
+// convertBoxOffice 按票房的lastUpdated当天的汇率把金额换算成to，原值放在Original里。
+// 换算不了（上游给了汇率表里没有的币种，或者日期早于汇率表）时金额和币种保持原样，标记Unconverted
+func (h *HandlerSet) convertBoxOffice(box *domain.BoxOffice, to string) {
+	if box == nil || to == "" || box.Currency == to {
+		return
+	}
+	q, err := h.Rates.Quote(box.Currency, to, box.LastUpdated)
+	if err != nil {
+		log.Printf("currency: convert box office from %s: %v", box.Currency, err)
+		box.Unconverted = true
+		return
+	}
+	box.Original = &domain.BoxOfficeOriginal{
+		Revenue:  box.Revenue,
+		Currency: box.Currency,
+		Rate:     q.Rate,
+		RateDate: q.Date.Format("2006-01-02"),
+	}
+	box.Revenue = domain.BoxOfficeRevenue{
+		Worldwide:         q.Apply(box.Revenue.Worldwide),
+		OpeningWeekendUSA: q.Apply(box.Revenue.OpeningWeekendUSA),
+	}
+	box.Currency = to
+}
+
+// convertMovie 换算票房和预算；预算用票房lastUpdated的汇率，没有票房时用最新汇率，换算不了时标记BudgetUnconverted
+func (h *HandlerSet) convertMovie(m *domain.Movie, to string) {
+	if to == "" {
+		return
+	}
+	at := time.Now()
+	if m.BoxOffice != nil {
+		at = m.BoxOffice.LastUpdated
+	}
+	h.convertBoxOffice(m.BoxOffice, to)
+
+	if m.Budget == nil || to == domain.BudgetCurrency {
+		return
+	}
+	q, err := h.Rates.Quote(domain.BudgetCurrency, to, at)
+	if err != nil {
+		log.Printf("currency: convert budget: %v", err)
+		m.BudgetUnconverted = true
+		return
+	}
+	m.BudgetOriginal = &domain.BudgetOriginal{
+		Amount:   *m.Budget,
+		Currency: domain.BudgetCurrency,
+		Rate:     q.Rate,
+		RateDate: q.Date.Format("2006-01-02"),
+	}
+	converted := q.Apply(*m.Budget)
+	m.Budget = &converted
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"interview"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /openapi.yml - API契约（内嵌在二进制里）
+func (h *HandlerSet) OpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", interview.OpenAPISpec)
+}
+
+// GET /docs - 跳转到Swagger UI
+func (h *HandlerSet) Docs(c *gin.Context) {
+	c.Redirect(http.StatusFound, "/swagger/index.html")
+}
@@ -1,22 +1,153 @@
 package handlers
 
 import (
+	"bytes"
+	"log"
+
 	"github.com/jmoiron/sqlx"
+	"interview"
+	"interview/internal/anomaly"
+	"interview/internal/audit"
 	"interview/internal/boxoffice"
 	"interview/internal/config"
+	"interview/internal/currency"
+	"interview/internal/enrichment"
+	"interview/internal/metrics"
+	"interview/internal/reconcile"
+	"interview/internal/refresh"
+	"interview/internal/validation"
 )
 
 type HandlerSet struct {
 	DB        *sqlx.DB
-	BoxOffice *boxoffice.Client
+	BoxOffice boxoffice.Provider
+	// Breakers 每个票房数据源的熔断器（按数据源名字），未启用熔断时为空
+	Breakers  map[string]*boxoffice.Breaker
+	Anomaly   *anomaly.Detector
+	Audit     *audit.Logger
+	Validator *validation.Validator
+	Metrics   *metrics.Registry
+	// AutoCorrect 创建时按上游改正的字段（BOXOFFICE_AUTOCORRECT），见reconcile.Check
+	AutoCorrect map[string]bool
+	// Rates 汇率表，?currency= 换算金额用
+	Rates *currency.Table
+	// Enrichment 异步票房补全队列，nil表示创建电影时同步调用上游
+	Enrichment *enrichment.Queue
+	// CachePurger BOXOFFICE_CACHE=postgres时的缓存，定期清理过期条目；其他模式为nil
+	CachePurger *boxoffice.PostgresCache
+	// Refresher 重新拉取已有电影的票房数据（定时任务由REFRESH_ENABLED控制）
+	Refresher *refresh.Refresher
 }
 
 func NewHandlerSet(db *sqlx.DB, cfg config.Config) *HandlerSet {
-	// 初始化BoxOffice客户端
-	boxOfficeClient := boxoffice.New(cfg.BoxofficeURL, cfg.BoxofficeAPIKey)
+	reg := metrics.New()
+	rates := loadExchangeRates(cfg)
+	provider, breakers := newBoxOfficeProvider(cfg, reg, rates)
+	provider, purger := withBoxOfficeCache(db, cfg, reg, provider)
+
+	auditLogger := audit.New(db)
+
+	var queue *enrichment.Queue
+	if cfg.BoxofficeAsync {
+		queue = enrichment.New(db, provider, auditLogger, cfg)
+	}
 
 	return &HandlerSet{
 		DB:        db,
-		BoxOffice: boxOfficeClient,
+		BoxOffice: provider,
+		Breakers:  breakers,
+		Anomaly:   anomaly.New(db, cfg),
+		Audit:     auditLogger,
+		Validator: validation.New(cfg.MovieGenres),
+		Metrics:   reg,
+		Rates:     rates,
+
+		AutoCorrect: reconcile.Set(cfg.BoxofficeAutoCorrect),
+
+		Enrichment:  queue,
+		CachePurger: purger,
+		Refresher:   refresh.New(db, provider, auditLogger, cfg),
+	}
+}
+
+// newBoxOfficeProvider 按配置创建票房数据源，每个数据源有自己的重试和熔断器；多个数据源时组合成Composite
+func newBoxOfficeProvider(cfg config.Config, reg *metrics.Registry, rates *currency.Table) (boxoffice.Provider, map[string]*boxoffice.Breaker) {
+	providerCfgs := cfg.BoxofficeProviders
+	if len(providerCfgs) == 0 {
+		providerCfgs = []config.BoxofficeProvider{{
+			Name:   boxoffice.DefaultSource,
+			Kind:   boxoffice.KindExample,
+			URL:    cfg.BoxofficeURL,
+			APIKey: cfg.BoxofficeAPIKey,
+		}}
+	}
+
+	breakers := map[string]*boxoffice.Breaker{}
+	var providers []boxoffice.Provider
+	for _, pc := range providerCfgs {
+		var breaker *boxoffice.Breaker
+		if cfg.BoxofficeBreakerEnabled {
+			breaker = boxoffice.NewBreaker(cfg.BoxofficeBreakerWindow, cfg.BoxofficeBreakerMinRequests,
+				cfg.BoxofficeBreakerFailureRate, cfg.BoxofficeBreakerCoolDown)
+			breakers[pc.Name] = breaker
+			reg.RegisterBreaker(pc.Name, breaker)
+		}
+
+		p, err := boxoffice.NewProvider(boxoffice.ProviderConfig{
+			Name:           pc.Name,
+			Kind:           pc.Kind,
+			URL:            pc.URL,
+			APIKey:         pc.APIKey,
+			AttemptTimeout: cfg.BoxofficeAttemptTimeout,
+			MaxRetries:     cfg.BoxofficeMaxRetries,
+			RetryBase:      cfg.BoxofficeRetryBase,
+			RetryMax:       cfg.BoxofficeRetryMax,
+			Breaker:        breaker,
+			Rates:          rates,
+		})
+		if err != nil {
+			log.Fatalf("failed to create box office provider: %v", err)
+		}
+		providers = append(providers, p)
+	}
+
+	// 标题写法不同（大小写、冠词位置、年份）时换几种写法再查，见boxoffice.Normalized
+	if len(providers) == 1 {
+		return boxoffice.NewNormalized(providers[0]), breakers
+	}
+	return boxoffice.NewNormalized(boxoffice.NewComposite(cfg.BoxofficeMerge, providers...)), breakers
+}
+
+// withBoxOfficeCache 按BOXOFFICE_CACHE在数据源前面加缓存
+func withBoxOfficeCache(db *sqlx.DB, cfg config.Config, reg *metrics.Registry, p boxoffice.Provider) (boxoffice.Provider, *boxoffice.PostgresCache) {
+	var cache boxoffice.Cache
+	var purger *boxoffice.PostgresCache
+	switch cfg.BoxofficeCache {
+	case "memory":
+		cache = boxoffice.NewLRUCache(cfg.BoxofficeCacheSize)
+	case "postgres":
+		purger = boxoffice.NewPostgresCache(db, p.Name())
+		cache = purger
+	default:
+		return p, nil
+	}
+	cached := boxoffice.NewCached(p, cache, cfg.BoxofficeCacheTTL, cfg.BoxofficeCacheNegativeTTL)
+	cached.FetchTimeout = cfg.BoxofficeCacheFetchTimeout
+	reg.RegisterCache(cached)
+	return cached, purger
+}
+
+// loadExchangeRates 读取EXCHANGE_RATES_FILE，没配置时用内置汇率表
+func loadExchangeRates(cfg config.Config) *currency.Table {
+	var rates *currency.Table
+	var err error
+	if cfg.ExchangeRatesFile != "" {
+		rates, err = currency.Load(cfg.ExchangeRatesFile)
+	} else {
+		rates, err = currency.Parse(bytes.NewReader(interview.ExchangeRates))
+	}
+	if err != nil {
+		log.Fatalf("failed to load exchange rates: %v", err)
 	}
+	return rates
 }
@@ -1,12 +1,38 @@
 package handlers
 
 import (
+	"interview/internal/boxoffice"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GET /healthz
+// GET /healthz - 任一票房数据源熔断器打开时status为degraded（仍然返回200，服务本身可用）
 func (h *HandlerSet) Healthz(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	status := "ok"
+	boxOffice := gin.H{"circuit": "disabled"}
+
+	if len(h.Breakers) > 0 {
+		// circuit取所有数据源里最差的状态
+		worst := boxoffice.StateClosed
+		providers := gin.H{}
+		for name, b := range h.Breakers {
+			st := b.Stats()
+			providers[name] = gin.H{
+				"circuit":  st.State.String(),
+				"openedAt": st.OpenedAt,
+				"rejected": st.Rejected,
+			}
+			worst = max(worst, st.State)
+		}
+		boxOffice = gin.H{"circuit": worst.String(), "providers": providers}
+		if worst != boxoffice.StateClosed {
+			status = "degraded"
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    status,
+		"upstreams": gin.H{"boxOffice": boxOffice},
+	})
 }
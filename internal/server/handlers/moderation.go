@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"interview/internal/apierror"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RatingHiddenRequest 隐藏/恢复评分
+type RatingHiddenRequest struct {
+	Hidden *bool `json:"hidden" binding:"required"`
+}
+
+// PUT /movies/:title/ratings/:raterId/hidden - 隐藏或恢复一条评分（版主）
+func (h *HandlerSet) SetRatingHidden(c *gin.Context) {
+	title := c.Param("title")
+	raterID := c.Param("raterId")
+
+	var req RatingHiddenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.BindError(err))
+		return
+	}
+
+	tx, err := h.DB.BeginTxx(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update rating"))
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(c.Request.Context(), `
+		UPDATE ratings
+		SET hidden = $1,
+		    hidden_by = CASE WHEN $1 THEN $4 END,
+		    hidden_at = CASE WHEN $1 THEN NOW() END
+		WHERE movie_title = $2 AND rater_id = $3
+	`, *req.Hidden, title, raterID, c.GetString("token_name"))
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update rating"))
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		apierror.Write(c, apierror.NotFound("Rating"))
+		return
+	}
+
+	resp := gin.H{
+		"movieTitle": title,
+		"raterId":    raterID,
+		"hidden":     *req.Hidden,
+	}
+	if err := h.recordAudit(c, tx, "rating.hide", "rating:"+title+"/"+raterID, nil, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update rating"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update rating"))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DELETE /admin/rating-anomalies/:title - 删除某部电影所有被标记为可疑的评分
+func (h *HandlerSet) PurgeSuspiciousRatings(c *gin.Context) {
+	title := c.Param("title")
+
+	tx, err := h.DB.BeginTxx(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to purge ratings"))
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(c.Request.Context(), "DELETE FROM ratings WHERE movie_title = $1 AND suspicious", title)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to purge ratings"))
+		return
+	}
+
+	n, _ := res.RowsAffected()
+	resp := gin.H{
+		"movieTitle": title,
+		"purged":     n,
+	}
+	if err := h.recordAudit(c, tx, "rating.purge", "movie:"+title, nil, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to purge ratings"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to purge ratings"))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
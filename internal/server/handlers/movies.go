@@ -4,22 +4,31 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"interview/internal/apierror"
+	"interview/internal/boxoffice"
 	"interview/internal/domain"
+	"interview/internal/enrichment"
+	"interview/internal/provenance"
+	"interview/internal/reconcile"
+	"interview/internal/snapshots"
+	"interview/internal/titles"
+	"interview/internal/validation"
 	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
-// MovieCreateRequest 创建电影的请求结构
+// MovieCreateRequest 创建电影的请求结构（字段校验见validateCreate，一次返回所有错误）
 type MovieCreateRequest struct {
-	Title       string  `json:"title" binding:"required"`
-	Genre       string  `json:"genre" binding:"required"`
-	ReleaseDate string  `json:"releaseDate" binding:"required"`
+	Title       string  `json:"title"`
+	Genre       string  `json:"genre"`
+	ReleaseDate string  `json:"releaseDate"`
 	Distributor *string `json:"distributor"`
 	Budget      *int64  `json:"budget"`
 	MpaRating   *string `json:"mpaRating"`
@@ -28,134 +37,464 @@ type MovieCreateRequest struct {
 // POST /movies
 func (h *HandlerSet) CreateMovie(c *gin.Context) {
 	var req MovieCreateRequest
-	// ShouldBindJSON 不会自动写入响应，但会添加错误到 c.Errors
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(422, gin.H{"code": "VALIDATION_ERROR", "message": "Invalid request body"})
+	// 类型不对的字段和其他字段的校验错误一起返回
+	bindErrs, err := apierror.BindLenient(c, &req)
+	if err != nil {
+		apierror.Write(c, err)
+		return
+	}
+	if err := h.validateCreate(&req, bindErrs); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	//minimal DB insert
-	var movieID int
-	err := h.DB.QueryRow(`
-		INSERT INTO movies (title, genre, release_date)
-		VALUES ($1, $2, $3)
-		RETURNING id
-	`, req.Title, req.Genre, req.ReleaseDate).Scan(&movieID)
+	// 异步模式：插入和入队在同一个事务里，立即返回
+	if h.Enrichment != nil {
+		h.createMovieAsync(c, req)
+		return
+	}
 
-	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			c.JSON(422, gin.H{"code": "VALIDATION_ERROR", "message": "Movie already exists"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "Failed to insert movie"})
+	if err := h.checkDuplicate(c.Request.Context(), req.Title, req.ReleaseDate); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	//call boxoffice (忽略错误，降级处理)
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// 先查上游并和用户填的值对齐，再在一个事务里插入，避免插入后再改标题时撞上唯一约束或留下半成品
+	//call boxoffice (忽略错误，降级处理)，按上映年份区分同名的翻拍
+	ctx, cancel := context.WithTimeout(boxoffice.WithYear(context.Background(), releaseYear(req.ReleaseDate)), 3*time.Second)
 	defer cancel()
 
-	boxData, _ := h.BoxOffice.FetchFull(ctx, req.Title)
+	boxData, fetchErr := h.BoxOffice.FetchFull(ctx, req.Title)
 
-	//merge fields (用户提供的值 > 上游返回的值)
-	var finalDistributor *string
-	var finalBudget *int64
-	var finalMpaRating *string
-	var box *domain.BoxOffice
+	// 上游成功应答（包括404）才记录请求时间，失败的留给定时刷新尽快补上
+	var fetchedAt *time.Time
+	if fetchErr == nil {
+		now := time.Now()
+		fetchedAt = &now
+	}
+
+	// 标题和上映日期和上游对比：不一致时返回warnings，按BOXOFFICE_AUTOCORRECT改正
+	meta, warnings := reconcile.Check(reconcile.Metadata{Title: req.Title, ReleaseDate: req.ReleaseDate}, boxData, h.AutoCorrect)
+	if meta.Title != req.Title && h.titleTaken(c.Request.Context(), meta.Title, 0) {
+		// 上游标题已经被别的电影用了，保留用户填的标题
+		meta.Title = req.Title
+		for i := range warnings {
+			if warnings[i].Field == reconcile.FieldTitle {
+				warnings[i].Corrected = false
+				warnings[i].Message += ", which is already used by another movie"
+			}
+		}
+	}
 
+	//merge fields (用户提供的值 > 上游返回的值)，记录每个字段的来源
+	userFields := provenance.Fields{Distributor: req.Distributor, Budget: req.Budget, MpaRating: req.MpaRating}
+	prov := provenance.User(nil, userFields, time.Now())
+	final := userFields
+	var box *domain.BoxOffice
 	if boxData != nil {
 		box = boxData.BoxOffice
+		final, prov = provenance.Merge(userFields, prov, boxData, *fetchedAt)
 	}
 
-	// Distributor: 用户提供 > boxoffice.distributor
-	if req.Distributor != nil {
-		finalDistributor = req.Distributor
-	} else if boxData != nil && boxData.Distributor != nil {
-		finalDistributor = boxData.Distributor
-	}
-
-	// Budget: 用户提供 > boxoffice.budget
-	if req.Budget != nil {
-		finalBudget = req.Budget
-	} else if boxData != nil && boxData.Budget != nil {
-		finalBudget = boxData.Budget
-	}
-
-	// MpaRating: 用户提供 > boxoffice.mpaRating
-	if req.MpaRating != nil {
-		finalMpaRating = req.MpaRating
-	} else if boxData != nil && boxData.MpaRating != nil {
-		finalMpaRating = boxData.MpaRating
-	}
-
-	//— 更新电影记录
-	_, err = h.DB.Exec(`
-		UPDATE movies
-		SET distributor = $1,
-		    budget = $2,
-		    mpa_rating = $3,
-		    boxoffice_revenue_worldwide = $4,
-		    boxoffice_revenue_opening_weekend_usa = $5,
-		    boxoffice_currency = $6,
-		    boxoffice_source = $7,
-		    boxoffice_last_updated = $8
-		WHERE id = $9
-	`, finalDistributor, finalBudget, finalMpaRating,
-		func() *int64 {
-			if box == nil {
-				return nil
-			}
-			return &box.Revenue.Worldwide
-		}(),
-		func() *int64 {
-			if box == nil {
-				return nil
-			}
-			return &box.Revenue.OpeningWeekendUSA
-		}(),
-		func() *string {
-			if box == nil {
-				return nil
-			}
-			return &box.Currency
-		}(),
-		func() *string {
-			if box == nil {
-				return nil
-			}
-			return &box.Source
-		}(),
-		func() *time.Time {
-			if box == nil {
-				return nil
-			}
-			return &box.LastUpdated
-		}(),
-		movieID,
-	)
+	//— 插入电影记录，票房数据追加为一条快照
+	tx, err := h.DB.BeginTxx(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	defer tx.Rollback()
 
+	var movieID int
+	err = tx.QueryRowContext(c.Request.Context(), `
+		INSERT INTO movies (title, genre, release_date, title_key, distributor, budget, mpa_rating, boxoffice_fetched_at, provenance)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, meta.Title, req.Genre, meta.ReleaseDate, titles.Key(meta.Title), final.Distributor, final.Budget, final.MpaRating,
+		fetchedAt, provenance.JSON(prov)).Scan(&movieID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "Failed to update movie"})
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Movie already exists"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
 		return
 	}
+	if fetchedAt != nil {
+		if err := snapshots.Record(c.Request.Context(), tx, movieID, box, *fetchedAt); err != nil {
+			apierror.Write(c, apierror.Internal("Failed to insert movie"))
+			return
+		}
+	}
 
 	// 构建响应
 	resp := domain.Movie{
 		ID:          fmt.Sprintf("%d", movieID),
-		Title:       req.Title,
+		Title:       meta.Title,
 		Genre:       req.Genre,
-		ReleaseDate: req.ReleaseDate,
-		Distributor: finalDistributor,
-		Budget:      finalBudget,
-		MpaRating:   finalMpaRating,
+		ReleaseDate: meta.ReleaseDate,
+		Distributor: final.Distributor,
+		Budget:      final.Budget,
+		MpaRating:   final.MpaRating,
 		BoxOffice:   box,
+		Provenance:  prov,
 	}
 
+	if err := h.recordAudit(c, tx, "movie.create", "movie:"+meta.Title, nil, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	withProvenance(c, &resp)
+	resp.Warnings = warnings
+
 	// 201 + Location header
+	c.Header("Location", "/movies/"+url.PathEscape(meta.Title))
+	c.JSON(http.StatusCreated, resp)
+}
+
+// createMovieAsync 只写用户提供的字段，票房数据由enrichment worker补全
+func (h *HandlerSet) createMovieAsync(c *gin.Context, req MovieCreateRequest) {
+	ctx := c.Request.Context()
+	if err := h.checkDuplicate(ctx, req.Title, req.ReleaseDate); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	defer tx.Rollback()
+
+	status := enrichment.StatusPending
+	prov := provenance.User(nil, provenance.Fields{Distributor: req.Distributor, Budget: req.Budget, MpaRating: req.MpaRating}, time.Now())
+	var movieID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO movies (title, genre, release_date, distributor, budget, mpa_rating, enrichment_status, provenance, title_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, req.Title, req.Genre, req.ReleaseDate, req.Distributor, req.Budget, req.MpaRating, status, provenance.JSON(prov),
+		titles.Key(req.Title)).Scan(&movieID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Movie already exists"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+
+	if err := h.Enrichment.Enqueue(ctx, tx, movieID, req.Title); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to enqueue box office enrichment"))
+		return
+	}
+
+	resp := domain.Movie{
+		ID:               fmt.Sprintf("%d", movieID),
+		Title:            req.Title,
+		Genre:            req.Genre,
+		ReleaseDate:      req.ReleaseDate,
+		Distributor:      req.Distributor,
+		Budget:           req.Budget,
+		MpaRating:        req.MpaRating,
+		EnrichmentStatus: &status,
+		Provenance:       prov,
+	}
+
+	if err := h.recordAudit(c, tx, "movie.create", "movie:"+req.Title, nil, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to insert movie"))
+		return
+	}
+	withProvenance(c, &resp)
+
 	c.Header("Location", "/movies/"+url.PathEscape(req.Title))
 	c.JSON(http.StatusCreated, resp)
 }
 
+// checkDuplicate 规范化后标题相同、上映年份也相同的电影算重复（"the dark knight" 和 "The Dark Knight"）。
+// movies.title是唯一的（评分也按title关联），同名不同年的翻拍要在标题末尾加上年份才能创建，如 "The Lion King (2019)"
+func (h *HandlerSet) checkDuplicate(ctx context.Context, title, releaseDate string) error {
+	year := releaseYear(releaseDate)
+	var existing string
+	var existingYear int
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT title, COALESCE(EXTRACT(YEAR FROM release_date)::int, 0) FROM movies
+		WHERE title_key = $1 AND (EXTRACT(YEAR FROM release_date) = $2 OR title = $3)
+		ORDER BY title = $3 DESC
+		LIMIT 1
+	`, titles.Key(title), year, title).Scan(&existing, &existingYear)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return apierror.Internal("Failed to check for duplicate movies")
+	}
+	if existingYear != year {
+		base, _ := titles.Split(title)
+		return apierror.New(apierror.CodeBadRequest,
+			fmt.Sprintf("Movie already exists; add the release year to the title for a remake, e.g. %q", fmt.Sprintf("%s (%d)", base, year)))
+	}
+	if existing == title {
+		return apierror.New(apierror.CodeBadRequest, "Movie already exists")
+	}
+	return apierror.New(apierror.CodeBadRequest, fmt.Sprintf("Movie already exists as %q", existing))
+}
+
+// releaseYear YYYY-MM-DD的年份，格式不对时返回0
+func releaseYear(date string) int {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0
+	}
+	return t.Year()
+}
+
+// likeEscaper 转义LIKE的通配符，配合 ESCAPE '\' 使用，q=% 只匹配标题里真的有%的电影
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// titleTaken 除了movieID以外是否已经有这个标题的电影（movieID为0时不排除任何电影）
+func (h *HandlerSet) titleTaken(ctx context.Context, title string, movieID int) bool {
+	var taken bool
+	err := h.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM movies WHERE title = $1 AND id <> $2)", title, movieID).Scan(&taken)
+	return err != nil || taken
+}
+
+// validateCreate 校验创建请求，title去掉首尾空白、genre规范成词表写法；bindErrs是解析时的类型错误
+func (h *HandlerSet) validateCreate(req *MovieCreateRequest, bindErrs []apierror.FieldError) error {
+	var errs validation.Errors
+	errs.Append(bindErrs...)
+	req.Title = strings.TrimSpace(req.Title)
+	h.Validator.Title(&errs, "/title", req.Title)
+	req.Genre = h.Validator.Genre(&errs, "/genre", req.Genre)
+	h.Validator.ReleaseDate(&errs, "/releaseDate", req.ReleaseDate)
+	h.Validator.Budget(&errs, "/budget", req.Budget)
+	h.Validator.MpaRating(&errs, "/mpaRating", req.MpaRating)
+	return errs.Err()
+}
+
+// movieColumns 与scanMovie的扫描顺序一致，票房字段来自最新的快照（见movieFrom）
+const movieColumns = "m.id, m.title, m.genre, m.release_date, m.distributor, m.budget, m.mpa_rating, " +
+	"bo.revenue_worldwide, bo.revenue_opening_weekend_usa, " +
+	"bo.currency, bo.source, bo.last_updated, m.enrichment_status, m.provenance"
+
+// movieFrom 配合movieColumns使用；m为movies或同结构的CTE
+func movieFrom(table string) string {
+	return " FROM " + table + " m " + snapshots.LatestJoin
+}
+
+// withProvenance 字段来源只在 ?include=provenance 时返回
+func withProvenance(c *gin.Context, movies ...*domain.Movie) {
+	for _, inc := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(inc) == "provenance" {
+			return
+		}
+	}
+	for _, m := range movies {
+		m.Provenance = nil
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMovie 把一行movies记录转换成domain.Movie
+func scanMovie(row rowScanner) (*domain.Movie, error) {
+	var m domain.Movie
+	var id int
+	var releaseDate time.Time
+	var distributor, mpaRating, boxCurrency, boxSource, enrichmentStatus sql.NullString
+	var budget, boxWorldwide, boxOpeningUSA sql.NullInt64
+	var boxLastUpdated sql.NullTime
+	var prov []byte
+
+	err := row.Scan(&id, &m.Title, &m.Genre, &releaseDate,
+		&distributor, &budget, &mpaRating,
+		&boxWorldwide, &boxOpeningUSA, &boxCurrency, &boxSource, &boxLastUpdated, &enrichmentStatus, &prov)
+	if err != nil {
+		return nil, err
+	}
+
+	m.ID = fmt.Sprintf("%d", id)
+	m.ReleaseDate = releaseDate.Format("2006-01-02")
+
+	if distributor.Valid {
+		m.Distributor = &distributor.String
+	}
+	if budget.Valid {
+		m.Budget = &budget.Int64
+	}
+	if mpaRating.Valid {
+		m.MpaRating = &mpaRating.String
+	}
+	if enrichmentStatus.Valid {
+		m.EnrichmentStatus = &enrichmentStatus.String
+	}
+	m.Provenance = provenance.Parse(prov)
+
+	// 构建BoxOffice对象
+	if boxWorldwide.Valid && boxCurrency.Valid && boxSource.Valid && boxLastUpdated.Valid {
+		m.BoxOffice = &domain.BoxOffice{
+			Revenue: domain.BoxOfficeRevenue{
+				Worldwide:         boxWorldwide.Int64,
+				OpeningWeekendUSA: boxOpeningUSA.Int64,
+			},
+			Currency:    boxCurrency.String,
+			Source:      boxSource.String,
+			LastUpdated: boxLastUpdated.Time,
+		}
+	}
+
+	return &m, nil
+}
+
+// MovieUpdateRequest 更新电影的请求结构，未提供的字段保持不变（title不可修改）
+type MovieUpdateRequest struct {
+	Genre       *string `json:"genre"`
+	ReleaseDate *string `json:"releaseDate"`
+	Distributor *string `json:"distributor"`
+	Budget      *int64  `json:"budget"`
+	MpaRating   *string `json:"mpaRating"`
+}
+
+// PATCH /movies/:title
+func (h *HandlerSet) UpdateMovie(c *gin.Context) {
+	title := c.Param("title")
+
+	var req MovieUpdateRequest
+	bindErrs, err := apierror.BindLenient(c, &req)
+	if err != nil {
+		apierror.Write(c, err)
+		return
+	}
+	if err := h.validateUpdate(&req, bindErrs); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update movie"))
+		return
+	}
+	defer tx.Rollback()
+
+	// 记录修改前的值用于审计，锁住这一行，避免并发修改让审计的before不准
+	before, err := scanMovie(tx.QueryRowContext(ctx, "SELECT "+movieColumns+movieFrom("movies")+" WHERE m.title = $1 FOR UPDATE OF m", title))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(c, apierror.NotFound("Movie"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to query movie"))
+		return
+	}
+
+	// 修改过的字段改记为用户填写，刷新时不再覆盖
+	userProv := provenance.User(nil, provenance.Fields{Distributor: req.Distributor, Budget: req.Budget, MpaRating: req.MpaRating}, time.Now())
+
+	row := tx.QueryRowContext(ctx, `
+		WITH updated AS (
+			UPDATE movies
+			SET genre = COALESCE($1, genre),
+			    release_date = COALESCE($2::DATE, release_date),
+			    distributor = COALESCE($3, distributor),
+			    budget = COALESCE($4, budget),
+			    mpa_rating = COALESCE($5, mpa_rating),
+			    provenance = provenance || $7::jsonb
+			WHERE title = $6
+			RETURNING *
+		)
+		SELECT `+movieColumns+movieFrom("updated"),
+		req.Genre, req.ReleaseDate, req.Distributor, req.Budget, req.MpaRating, title, provenance.JSON(userProv))
+
+	m, err := scanMovie(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(c, apierror.NotFound("Movie"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to update movie"))
+		return
+	}
+
+	if err := h.recordAudit(c, tx, "movie.update", "movie:"+title, before, m); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update movie"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to update movie"))
+		return
+	}
+	withProvenance(c, m)
+	c.JSON(http.StatusOK, m)
+}
+
+// validateUpdate 只校验提供了的字段；bindErrs是解析时的类型错误
+func (h *HandlerSet) validateUpdate(req *MovieUpdateRequest, bindErrs []apierror.FieldError) error {
+	var errs validation.Errors
+	errs.Append(bindErrs...)
+	if req.Genre != nil {
+		genre := h.Validator.Genre(&errs, "/genre", *req.Genre)
+		req.Genre = &genre
+	}
+	if req.ReleaseDate != nil {
+		h.Validator.ReleaseDate(&errs, "/releaseDate", *req.ReleaseDate)
+	}
+	h.Validator.Budget(&errs, "/budget", req.Budget)
+	h.Validator.MpaRating(&errs, "/mpaRating", req.MpaRating)
+	return errs.Err()
+}
+
+// DELETE /movies/:title（评分通过外键级联删除）
+func (h *HandlerSet) DeleteMovie(c *gin.Context) {
+	title := c.Param("title")
+
+	ctx := c.Request.Context()
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to delete movie"))
+		return
+	}
+	defer tx.Rollback()
+
+	// CTE里的查询看到的是删除前的快照，级联删除的票房快照还能取到
+	row := tx.QueryRowContext(ctx, "WITH deleted AS (DELETE FROM movies WHERE title = $1 RETURNING *) SELECT "+
+		movieColumns+movieFrom("deleted"), title)
+	before, err := scanMovie(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(c, apierror.NotFound("Movie"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to delete movie"))
+		return
+	}
+
+	if err := h.recordAudit(c, tx, "movie.delete", "movie:"+title, before, nil); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to delete movie"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to delete movie"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 // GET /movies - 列表和搜索
 func (h *HandlerSet) ListMovies(c *gin.Context) {
 	// 解析查询参数
@@ -168,6 +507,12 @@ func (h *HandlerSet) ListMovies(c *gin.Context) {
 	limitStr := c.Query("limit")
 	cursor := c.Query("cursor")
 
+	// 金额换算成指定币种（可选）
+	toCurrency, ok := h.targetCurrency(c)
+	if !ok {
+		return
+	}
+
 	// 默认limit
 	limit := 20
 	if limitStr != "" {
@@ -185,19 +530,21 @@ func (h *HandlerSet) ListMovies(c *gin.Context) {
 	}
 
 	// 构建SQL查询
-	query := "SELECT id, title, genre, release_date, distributor, budget, mpa_rating, " +
-		"boxoffice_revenue_worldwide, boxoffice_revenue_opening_weekend_usa, " +
-		"boxoffice_currency, boxoffice_source, boxoffice_last_updated " +
-		"FROM movies WHERE 1=1"
+	query := "SELECT " + movieColumns + movieFrom("movies") + " WHERE 1=1"
 
 	args := []interface{}{}
 	argIdx := 1
 
 	// 关键词搜索（模糊匹配title）
 	if q != "" {
-		query += fmt.Sprintf(" AND title ILIKE $%d", argIdx)
-		args = append(args, "%"+q+"%")
-		argIdx++
+		// 也按规范化的标题匹配，"Dark Knight, The" 能搜到 "The Dark Knight"
+		key := titles.Key(q)
+		if key == "" {
+			key = q
+		}
+		query += fmt.Sprintf(` AND (title ILIKE $%d ESCAPE '\' OR title_key LIKE $%d ESCAPE '\')`, argIdx, argIdx+1)
+		args = append(args, "%"+escapeLike(q)+"%", "%"+escapeLike(key)+"%")
+		argIdx += 2
 	}
 
 	// 年份过滤
@@ -246,52 +593,20 @@ func (h *HandlerSet) ListMovies(c *gin.Context) {
 	// 执行查询
 	rows, err := h.DB.Query(query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "Failed to query movies"})
+		apierror.Write(c, apierror.Internal("Failed to query movies"))
 		return
 	}
 	defer rows.Close()
 
 	movies := []domain.Movie{}
 	for rows.Next() {
-		var m domain.Movie
-		var id int
-		var distributor, mpaRating, boxCurrency, boxSource sql.NullString
-		var budget, boxWorldwide, boxOpeningUSA sql.NullInt64
-		var boxLastUpdated sql.NullTime
-
-		err := rows.Scan(&id, &m.Title, &m.Genre, &m.ReleaseDate,
-			&distributor, &budget, &mpaRating,
-			&boxWorldwide, &boxOpeningUSA, &boxCurrency, &boxSource, &boxLastUpdated)
+		m, err := scanMovie(rows)
 		if err != nil {
 			continue
 		}
-
-		m.ID = fmt.Sprintf("%d", id)
-
-		if distributor.Valid {
-			m.Distributor = &distributor.String
-		}
-		if budget.Valid {
-			m.Budget = &budget.Int64
-		}
-		if mpaRating.Valid {
-			m.MpaRating = &mpaRating.String
-		}
-
-		// 构建BoxOffice对象
-		if boxWorldwide.Valid && boxCurrency.Valid && boxSource.Valid && boxLastUpdated.Valid {
-			m.BoxOffice = &domain.BoxOffice{
-				Revenue: domain.BoxOfficeRevenue{
-					Worldwide:         boxWorldwide.Int64,
-					OpeningWeekendUSA: boxOpeningUSA.Int64,
-				},
-				Currency:    boxCurrency.String,
-				Source:      boxSource.String,
-				LastUpdated: boxLastUpdated.Time,
-			}
-		}
-
-		movies = append(movies, m)
+		withProvenance(c, m)
+		h.convertMovie(m, toCurrency)
+		movies = append(movies, *m)
 	}
 
 	// 判断是否有下一页
@@ -318,7 +633,7 @@ func (h *HandlerSet) SubmitRating(c *gin.Context) {
 	}
 	// ShouldBindJSON 不会自动写入响应
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(422, gin.H{"code": "VALIDATION_ERROR", "message": "Invalid request body"})
+		apierror.Write(c, apierror.BindError(err))
 		return
 	}
 
@@ -332,7 +647,7 @@ func (h *HandlerSet) SubmitRating(c *gin.Context) {
 		}
 	}
 	if !isValid {
-		c.JSON(422, gin.H{"code": "INVALID_RATING", "message": "Rating must be one of: 0.5, 1.0, 1.5, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5.0"})
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Rating must be one of: 0.5, 1.0, 1.5, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5.0"))
 		return
 	}
 
@@ -340,27 +655,38 @@ func (h *HandlerSet) SubmitRating(c *gin.Context) {
 	var exists bool
 	err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM movies WHERE title = $1)", title).Scan(&exists)
 	if err != nil || !exists {
-		c.JSON(http.StatusNotFound, gin.H{"code": "NOT_FOUND", "message": "Movie not found"})
+		apierror.Write(c, apierror.NotFound("Movie"))
 		return
 	}
 
-	// 先检查评分是否已存在
-	var existingRating sql.NullFloat64
-	err = h.DB.QueryRow("SELECT rating FROM ratings WHERE movie_title = $1 AND rater_id = $2",
-		title, raterID).Scan(&existingRating)
-
-	isNew := err == sql.ErrNoRows
+	ctx := c.Request.Context()
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save rating"))
+		return
+	}
+	defer tx.Rollback()
 
-	// Upsert评分
-	_, err = h.DB.Exec(`
-		INSERT INTO ratings (movie_title, rater_id, rating, updated_at)
-		VALUES ($1, $2, $3, NOW())
-		ON CONFLICT (movie_title, rater_id)
-		DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
-	`, title, raterID, req.Rating)
+	// Upsert评分，用 xmax = 0 判断是插入还是更新（先查再写在并发时会判断错），prev取修改前的值写审计
+	var isNew bool
+	var existingRating sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `
+		WITH prev AS (
+			SELECT rating FROM ratings
+			WHERE movie_title = $1 AND rater_id = $2
+			FOR UPDATE
+		), upserted AS (
+			INSERT INTO ratings (movie_title, rater_id, rating, updated_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (movie_title, rater_id)
+			DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
+			RETURNING (xmax = 0) AS inserted
+		)
+		SELECT upserted.inserted, prev.rating FROM upserted LEFT JOIN prev ON TRUE
+	`, title, raterID, req.Rating).Scan(&isNew, &existingRating)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "Failed to save rating"})
+		apierror.Write(c, apierror.Internal("Failed to save rating"))
 		return
 	}
 
@@ -370,6 +696,22 @@ func (h *HandlerSet) SubmitRating(c *gin.Context) {
 		"rating":     req.Rating,
 	}
 
+	var before interface{}
+	if !isNew && existingRating.Valid {
+		before = gin.H{"movieTitle": title, "raterId": raterID, "rating": existingRating.Float64}
+	}
+	if err := h.recordAudit(c, tx, "rating.upsert", fmt.Sprintf("rating:%s/%v", title, raterID), before, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save rating"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save rating"))
+		return
+	}
+
+	// 刷分检测在后台进行，不拖慢评分提交
+	h.Anomaly.Schedule(title)
+
 	// 根据是否是新记录返回不同的状态码
 	if isNew {
 		c.Header("Location", fmt.Sprintf("/movies/%s/ratings", url.PathEscape(title)))
@@ -387,21 +729,22 @@ func (h *HandlerSet) GetRating(c *gin.Context) {
 	var exists bool
 	err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM movies WHERE title = $1)", title).Scan(&exists)
 	if err != nil || !exists {
-		c.JSON(http.StatusNotFound, gin.H{"code": "NOT_FOUND", "message": "Movie not found"})
+		apierror.Write(c, apierror.NotFound("Movie"))
 		return
 	}
 
-	// 计算平均分和数量
+	// 计算平均分和数量（不含被隐藏的评分，可配置排除被标记为可疑的评分）
+	query := "SELECT AVG(rating), COUNT(*) FROM ratings WHERE movie_title = $1 AND NOT hidden"
+	if h.Anomaly.ExcludeSuspicious {
+		query += " AND NOT suspicious"
+	}
+
 	var avg sql.NullFloat64
 	var count int
-	err = h.DB.QueryRow(`
-		SELECT AVG(rating), COUNT(*)
-		FROM ratings
-		WHERE movie_title = $1
-	`, title).Scan(&avg, &count)
+	err = h.DB.QueryRow(query, title).Scan(&avg, &count)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "INTERNAL_ERROR", "message": "Failed to calculate rating"})
+		apierror.Write(c, apierror.Internal("Failed to calculate rating"))
 		return
 	}
 
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"interview/internal/apierror"
+	"interview/internal/enrichment"
+	"interview/internal/refresh"
+	"interview/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// 批量刷新一次最多处理的电影数。在请求里同步执行，上限要小，大批量的交给定时刷新
+const (
+	defaultRefreshLimit = 10
+	maxRefreshLimit     = 20
+)
+
+// BulkRefreshRequest 批量刷新的过滤条件，多个条件同时满足（AND）
+type BulkRefreshRequest struct {
+	Titles           []string   `json:"titles"`
+	EnrichmentStatus *string    `json:"enrichmentStatus"`
+	MissingBoxOffice bool       `json:"missingBoxOffice"`
+	FetchedBefore    *time.Time `json:"fetchedBefore"`
+	Limit            *int       `json:"limit"`
+}
+
+// POST /movies/:title/boxoffice/refresh - 重新拉取一部电影的票房数据，返回变化的字段
+func (h *HandlerSet) RefreshMovieBoxOffice(c *gin.Context) {
+	title := c.Param("title")
+
+	// 有变化时审计和刷新在同一个事务里写入
+	res, err := h.Refresher.RefreshMovie(c.Request.Context(), title, auditActor(c))
+	if err != nil {
+		apierror.Write(c, refreshError(err))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// POST /admin/boxoffice/refresh - 按过滤条件批量刷新，单部失败不影响其他电影
+func (h *HandlerSet) BulkRefreshBoxOffice(c *gin.Context) {
+	var req BulkRefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.BindError(err))
+		return
+	}
+
+	var errs validation.Errors
+	if len(req.Titles) == 0 && req.EnrichmentStatus == nil && !req.MissingBoxOffice && req.FetchedBefore == nil {
+		errs.Add("", "at least one filter is required")
+	}
+	if s := req.EnrichmentStatus; s != nil && *s != enrichment.StatusPending && *s != enrichment.StatusDone && *s != enrichment.StatusFailed {
+		errs.Add("/enrichmentStatus", "must be one of: pending, done, failed")
+	}
+	limit := defaultRefreshLimit
+	if req.Limit != nil {
+		limit = *req.Limit
+		if limit < 1 || limit > maxRefreshLimit {
+			errs.Add("/limit", "must be between 1 and %d", maxRefreshLimit)
+		}
+	}
+	if err := errs.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	// 构建SQL查询
+	query := "SELECT title FROM movies WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if len(req.Titles) > 0 {
+		query += fmt.Sprintf(" AND title = ANY($%d)", argIdx)
+		args = append(args, pq.Array(req.Titles))
+		argIdx++
+	}
+	if req.EnrichmentStatus != nil {
+		query += fmt.Sprintf(" AND enrichment_status = $%d", argIdx)
+		args = append(args, *req.EnrichmentStatus)
+		argIdx++
+	}
+	if req.MissingBoxOffice {
+		query += " AND NOT EXISTS (SELECT 1 FROM boxoffice_snapshots s WHERE s.movie_id = movies.id)"
+	}
+	if req.FetchedBefore != nil {
+		query += fmt.Sprintf(" AND (boxoffice_fetched_at IS NULL OR boxoffice_fetched_at < $%d)", argIdx)
+		args = append(args, *req.FetchedBefore)
+		argIdx++
+	}
+	query += fmt.Sprintf(" ORDER BY release_date DESC, id LIMIT $%d", argIdx)
+	args = append(args, limit)
+
+	var titles []string
+	if err := h.DB.SelectContext(c.Request.Context(), &titles, query, args...); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to select movies"))
+		return
+	}
+
+	report := h.Refresher.RefreshTitles(c.Request.Context(), titles, auditActor(c))
+	c.JSON(http.StatusOK, report)
+}
+
+func refreshError(err error) error {
+	if errors.Is(err, refresh.ErrMovieNotFound) {
+		return apierror.NotFound("Movie")
+	}
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	// 上游的错误信息（地址、响应内容）不返回给调用方，只记日志
+	log.Printf("boxoffice refresh failed: %v", err)
+	return apierror.New(apierror.CodeUpstream, "Box office upstream unavailable")
+}
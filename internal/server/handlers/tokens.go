@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"interview/internal/apierror"
+	"interview/internal/auth"
+	"interview/internal/domain"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// POST /admin/tokens - 创建API token
+func (h *HandlerSet) CreateToken(c *gin.Context) {
+	var req domain.APITokenCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.BindError(err))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || (req.Role == nil && len(req.Scopes) == 0) {
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest, "name and a role or at least one scope are required"))
+		return
+	}
+	if req.Role != nil && !auth.ValidRole(*req.Role) {
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Invalid role: "+*req.Role))
+		return
+	}
+	if req.Scopes == nil {
+		req.Scopes = []string{}
+	}
+	for _, s := range req.Scopes {
+		if !auth.ValidScope(s) {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Invalid scope: "+s))
+			return
+		}
+	}
+	// 只能授予调用方自己拥有的权限，否则持有tokens:manage的非admin调用方可以给自己发一个admin token
+	v, _ := c.Get("token_scopes")
+	granted, _ := v.([]string)
+	if req.Role != nil {
+		for _, p := range auth.RolePermissions[*req.Role] {
+			if !auth.HasScope(granted, p) {
+				apierror.Write(c, apierror.New(apierror.CodeForbidden, "Cannot grant role "+*req.Role))
+				return
+			}
+		}
+	}
+	for _, s := range req.Scopes {
+		if !auth.HasScope(granted, s) {
+			apierror.Write(c, apierror.New(apierror.CodeForbidden, "Cannot grant scope "+s))
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest, "expiresAt must be in the future"))
+		return
+	}
+
+	token, err := auth.GenerateAPIToken()
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to generate token"))
+		return
+	}
+
+	tx, err := h.DB.BeginTxx(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to create token"))
+		return
+	}
+	defer tx.Rollback()
+
+	var createdAt time.Time
+	err = tx.QueryRowContext(c.Request.Context(), `
+		INSERT INTO api_tokens (name, token_hash, role, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, req.Name, auth.HashAPIToken(token), req.Role, pq.Array(req.Scopes), req.ExpiresAt).Scan(&createdAt)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			apierror.Write(c, apierror.New(apierror.CodeBadRequest, "Token name already exists"))
+			return
+		}
+		apierror.Write(c, apierror.Internal("Failed to create token"))
+		return
+	}
+
+	resp := domain.APITokenCreated{
+		APIToken: domain.APIToken{
+			Name:      req.Name,
+			Role:      req.Role,
+			Scopes:    req.Scopes,
+			ExpiresAt: req.ExpiresAt,
+			CreatedAt: createdAt,
+		},
+		Token: token,
+	}
+
+	// 审计里不能出现token明文
+	if err := h.recordAudit(c, tx, "token.create", "token:"+req.Name, nil, resp.APIToken); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to create token"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to create token"))
+		return
+	}
+
+	c.Header("Location", "/admin/tokens/"+url.PathEscape(req.Name))
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GET /admin/tokens - token列表（不返回明文和哈希）
+func (h *HandlerSet) ListTokens(c *gin.Context) {
+	rows, err := h.DB.Query(`
+		SELECT name, role, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to query tokens"))
+		return
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		var t domain.APIToken
+		var role sql.NullString
+		var scopes pq.StringArray
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&t.Name, &role, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &t.CreatedAt); err != nil {
+			continue
+		}
+
+		t.Scopes = []string(scopes)
+		if role.Valid {
+			t.Role = &role.String
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			t.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": tokens})
+}
+
+// DELETE /admin/tokens/:name - 吊销token
+func (h *HandlerSet) RevokeToken(c *gin.Context) {
+	name := c.Param("name")
+
+	tx, err := h.DB.BeginTxx(c.Request.Context(), nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to revoke token"))
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(c.Request.Context(), `
+		UPDATE api_tokens
+		SET revoked_at = NOW()
+		WHERE name = $1 AND revoked_at IS NULL
+	`, name)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to revoke token"))
+		return
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		apierror.Write(c, apierror.NotFound("Token"))
+		return
+	}
+
+	if err := h.recordAudit(c, tx, "token.revoke", "token:"+name, nil, gin.H{"name": name, "revoked": true}); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to revoke token"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to revoke token"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"interview/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 授予调用方没有的权限在访问数据库之前就被拒绝，所以HandlerSet不需要DB
+func TestCreateTokenRejectsUngrantedPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		caller []string
+		body   string
+	}{
+		{"admin role", []string{auth.ScopeTokensManage}, `{"name":"escalate","role":"admin"}`},
+		{"role with a missing permission", []string{auth.ScopeTokensManage}, `{"name":"escalate","role":"editor"}`},
+		{"missing scope", []string{auth.ScopeTokensManage}, `{"name":"escalate","scopes":["audit:read"]}`},
+		{"one of several scopes missing", []string{auth.ScopeTokensManage, auth.ScopeMoviesWrite},
+			`{"name":"escalate","scopes":["movies:write","movies:delete"]}`},
+		{"no permissions", nil, `{"name":"escalate","role":"moderator"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/admin/tokens", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("token_scopes", auth.Permissions(nil, tt.caller))
+
+			(&HandlerSet{}).CreateToken(c)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("CreateToken() status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+			}
+			var body struct{ Code string }
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != "FORBIDDEN" {
+				t.Errorf("CreateToken() body = %s, want code FORBIDDEN", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"interview/internal/apierror"
+	"interview/internal/domain"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /movies/:title/ratings - 评分列表（支持按有用性排序）
+func (h *HandlerSet) ListReviews(c *gin.Context) {
+	title := c.Param("title")
+	sortBy := c.DefaultQuery("sort", "recent")
+	limitStr := c.Query("limit")
+	cursor := c.Query("cursor")
+
+	if sortBy != "recent" && sortBy != "helpful" {
+		apierror.Write(c, apierror.New(apierror.CodeBadRequest, "sort must be one of: recent, helpful"))
+		return
+	}
+
+	// 默认limit
+	limit := 20
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	// 解析cursor（与ListMovies一致：offset）
+	offset := 0
+	if cursor != "" {
+		if o, err := strconv.Atoi(cursor); err == nil && o > 0 {
+			offset = o
+		}
+	}
+
+	// 检查电影是否存在
+	var exists bool
+	err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM movies WHERE title = $1)", title).Scan(&exists)
+	if err != nil || !exists {
+		apierror.Write(c, apierror.NotFound("Movie"))
+		return
+	}
+
+	// helpful: 按净有用票数排序，其次有用票数，最后按更新时间
+	orderBy := "r.updated_at DESC, r.rater_id"
+	if sortBy == "helpful" {
+		orderBy = "(COALESCE(v.helpful_count, 0) - COALESCE(v.unhelpful_count, 0)) DESC, " +
+			"COALESCE(v.helpful_count, 0) DESC, r.updated_at DESC, r.rater_id"
+	}
+
+	query := `
+		SELECT r.movie_title, r.rater_id, r.rating, r.updated_at,
+		       COALESCE(v.helpful_count, 0), COALESCE(v.unhelpful_count, 0)
+		FROM ratings r
+		LEFT JOIN (
+			SELECT movie_title, rater_id,
+			       COUNT(*) FILTER (WHERE helpful) AS helpful_count,
+			       COUNT(*) FILTER (WHERE NOT helpful) AS unhelpful_count
+			FROM rating_votes
+			WHERE movie_title = $1
+			GROUP BY movie_title, rater_id
+		) v ON v.movie_title = r.movie_title AND v.rater_id = r.rater_id
+		WHERE r.movie_title = $1 AND NOT r.hidden
+		ORDER BY ` + orderBy + `
+		LIMIT $2 OFFSET $3`
+
+	rows, err := h.DB.Query(query, title, limit+1, offset) // 多查一条判断是否有下一页
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to query ratings"))
+		return
+	}
+	defer rows.Close()
+
+	reviews := []domain.Review{}
+	for rows.Next() {
+		var r domain.Review
+		if err := rows.Scan(&r.MovieTitle, &r.RaterID, &r.Rating, &r.UpdatedAt,
+			&r.HelpfulCount, &r.UnhelpfulCount); err != nil {
+			continue
+		}
+		reviews = append(reviews, r)
+	}
+
+	// 判断是否有下一页
+	var nextCursor *string
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+		next := fmt.Sprintf("%d", offset+limit)
+		nextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":      reviews,
+		"nextCursor": nextCursor,
+	})
+}
+
+// POST /movies/:title/ratings/:raterId/votes - 对他人评分投票（Upsert）
+func (h *HandlerSet) SubmitVote(c *gin.Context) {
+	title := c.Param("title")
+	raterID := c.Param("raterId")
+	voterID := c.GetString("rater_id")
+
+	var req domain.VoteSubmit
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, apierror.BindError(err))
+		return
+	}
+
+	// 不允许给自己的评分投票
+	if voterID == raterID {
+		apierror.Write(c, apierror.New(apierror.CodeForbidden, "Cannot vote on your own rating"))
+		return
+	}
+
+	// 检查被投票的评分是否存在
+	var exists bool
+	err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM ratings WHERE movie_title = $1 AND rater_id = $2)",
+		title, raterID).Scan(&exists)
+	if err != nil || !exists {
+		apierror.Write(c, apierror.NotFound("Rating"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save vote"))
+		return
+	}
+	defer tx.Rollback()
+
+	// Upsert投票。xmax = 0 表示这次是插入而不是更新，先查再写在并发时会判断错；
+	// prev在同一条语句里锁住并取出修改前的值，写审计用（并发插入时可能取不到，before为空）
+	var isNew bool
+	var existing sql.NullBool
+	err = tx.QueryRowContext(ctx, `
+		WITH prev AS (
+			SELECT helpful FROM rating_votes
+			WHERE movie_title = $1 AND rater_id = $2 AND voter_id = $3
+			FOR UPDATE
+		), upserted AS (
+			INSERT INTO rating_votes (movie_title, rater_id, voter_id, helpful, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (movie_title, rater_id, voter_id)
+			DO UPDATE SET helpful = EXCLUDED.helpful, updated_at = NOW()
+			RETURNING (xmax = 0) AS inserted
+		)
+		SELECT upserted.inserted, prev.helpful FROM upserted LEFT JOIN prev ON TRUE
+	`, title, raterID, voterID, *req.Helpful).Scan(&isNew, &existing)
+
+	if err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save vote"))
+		return
+	}
+
+	resp := domain.VoteResult{
+		MovieTitle: title,
+		RaterID:    raterID,
+		VoterID:    voterID,
+		Helpful:    *req.Helpful,
+	}
+
+	var before interface{}
+	if !isNew && existing.Valid {
+		before = domain.VoteResult{MovieTitle: title, RaterID: raterID, VoterID: voterID, Helpful: existing.Bool}
+	}
+	if err := h.recordAudit(c, tx, "vote.upsert", fmt.Sprintf("rating:%s/%s", title, raterID), before, resp); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save vote"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		apierror.Write(c, apierror.Internal("Failed to save vote"))
+		return
+	}
+
+	if isNew {
+		c.Header("Location", fmt.Sprintf("/movies/%s/ratings/%s/votes", url.PathEscape(title), url.PathEscape(raterID)))
+		c.JSON(http.StatusCreated, resp)
+	} else {
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -1,48 +1,141 @@
 package middleware
 
 import (
-	"net/http"
+	"crypto/subtle"
+	"database/sql"
+	"log"
 	"strings"
+	"time"
+
+	"interview/internal/apierror"
+	"interview/internal/auth"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
-// 验证Bearer Token
+// 静态AUTH_TOKEN作为引导用的admin token
+const staticTokenName = "static"
+
+// 验证Bearer Token（静态AUTH_TOKEN或api_tokens表中的token）
 func (m *MiddlewareSet) AuthBearer() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取Authorization头
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Missing or invalid authentication information",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.ErrUnauthorized)
 			return
 		}
 
 		// 检查格式 "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Missing or invalid authentication information",
-			})
-			c.Abort()
+			apierror.Abort(c, apierror.ErrUnauthorized)
 			return
 		}
 
 		// 验证token
 		token := parts[1]
-		if token != m.Config.AuthToken {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Missing or invalid authentication information",
-			})
-			c.Abort()
+		if subtle.ConstantTimeCompare([]byte(token), []byte(m.Config.AuthToken)) == 1 {
+			setIdentity(c, staticTokenName, []string{auth.RoleAdmin}, nil)
+			c.Next()
+			return
+		}
+
+		// 配置了JWKS时，三段式token按JWT校验
+		if m.JWT != nil && auth.LooksLikeJWT(token) {
+			claims, err := m.JWT.Verify(c.Request.Context(), token, time.Now())
+			if err != nil {
+				apierror.Abort(c, apierror.ErrUnauthorized)
+				return
+			}
+			m.setJWTIdentity(c, claims)
+			c.Next()
 			return
 		}
 
+		var name string
+		var role sql.NullString
+		var scopes pq.StringArray
+		var stale bool
+		hash := auth.HashAPIToken(token)
+		interval := m.Config.TokenLastUsedInterval.Seconds()
+		err := m.DB.QueryRowContext(c.Request.Context(), `
+			SELECT name, role, scopes,
+			       last_used_at IS NULL OR last_used_at < NOW() - $2::float8 * INTERVAL '1 second'
+			FROM api_tokens
+			WHERE token_hash = $1
+			  AND revoked_at IS NULL
+			  AND (expires_at IS NULL OR expires_at > NOW())
+		`, hash, interval).Scan(&name, &role, &scopes, &stale)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("api token lookup failed: %v", err)
+			}
+			apierror.Abort(c, apierror.ErrUnauthorized)
+			return
+		}
+		if stale {
+			m.touchToken(c, hash, interval)
+		}
+
+		var roles []string
+		if role.Valid {
+			roles = []string{role.String}
+		}
+		setIdentity(c, name, roles, scopes)
 		c.Next()
 	}
 }
+
+// touchToken 更新last_used_at。距上次记录不到TOKEN_LAST_USED_INTERVAL的请求不会调用，避免每个请求都写一次库；
+// 条件也写在UPDATE里，并发的请求只有一个会真正更新。失败只记日志，不影响鉴权
+func (m *MiddlewareSet) touchToken(c *gin.Context, hash string, interval float64) {
+	_, err := m.DB.ExecContext(c.Request.Context(), `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		WHERE token_hash = $1
+		  AND (last_used_at IS NULL OR last_used_at < NOW() - $2::float8 * INTERVAL '1 second')
+	`, hash, interval)
+	if err != nil {
+		log.Printf("api token last_used_at update failed: %v", err)
+	}
+}
+
+// RequireScope 要求token（角色展开后）带有指定权限，必须放在AuthBearer之后
+func (m *MiddlewareSet) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("token_scopes")
+		granted, _ := scopes.([]string)
+		if !auth.HasScope(granted, scope) {
+			apierror.Abort(c, apierror.ErrForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setIdentity 把调用方名称、角色和展开后的权限写进context
+func setIdentity(c *gin.Context, name string, roles, scopes []string) {
+	c.Set("token_name", name)
+	c.Set("token_roles", roles)
+	c.Set("token_scopes", auth.Permissions(roles, scopes))
+}
+
+// setJWTIdentity 把JWT的角色、scope和rater身份写进context
+func (m *MiddlewareSet) setJWTIdentity(c *gin.Context, claims *auth.Claims) {
+	setIdentity(c, "jwt:"+claims.Subject,
+		claims.Strings(m.Config.JWTRolesClaim), claims.Strings(m.Config.JWTScopesClaim))
+	if raterID := m.jwtRaterID(claims); raterID != "" {
+		c.Set("rater_id", raterID)
+	}
+}
+
+// jwtRaterID 从JWT_RATER_CLAIM声明取rater id
+func (m *MiddlewareSet) jwtRaterID(claims *auth.Claims) string {
+	if m.Config.JWTRaterClaim == "" || m.Config.JWTRaterClaim == "sub" {
+		return claims.Subject
+	}
+	return claims.String(m.Config.JWTRaterClaim)
+}
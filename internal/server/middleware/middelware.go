@@ -1,11 +1,58 @@
 package middleware
 
-import "interview/internal/config"
+import (
+	"context"
+	"interview/internal/auth"
+	"interview/internal/config"
+	"log"
+	"time"
+
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/jmoiron/sqlx"
+)
 
 type MiddlewareSet struct {
+	DB     *sqlx.DB
 	Config config.Config
+
+	// JWT 校验Bearer JWT，未配置JWT_JWKS时为nil
+	JWT *auth.Verifier
+	// RaterJWT RATER_AUTH_MODE=jwt时校验rater的JWT
+	RaterJWT *auth.Verifier
+	// OpenAPI 契约路由表，OPENAPI_VALIDATION=off时为nil
+	OpenAPI routers.Router
 }
 
-func NewMiddlewareSet(cfg config.Config) *MiddlewareSet {
-	return &MiddlewareSet{Config: cfg}
+func NewMiddlewareSet(db *sqlx.DB, cfg config.Config) *MiddlewareSet {
+	m := &MiddlewareSet{DB: db, Config: cfg}
+
+	if cfg.JWTJWKS != "" {
+		keys, err := auth.LoadJWKS(context.Background(), cfg.JWTJWKS)
+		if err != nil {
+			log.Fatalf("failed to load jwks: %v", err)
+		}
+		m.JWT = &auth.Verifier{
+			Keys:     keys,
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Leeway:   time.Minute,
+		}
+	}
+
+	if cfg.RaterAuthMode == RaterAuthJWT {
+		m.RaterJWT = m.JWT
+		if m.RaterJWT == nil {
+			m.RaterJWT = &auth.Verifier{Keys: auth.NewHMACKeySet([]byte(cfg.RaterTokenSecret)), Leeway: time.Minute}
+		}
+	}
+
+	if cfg.OpenAPIValidation != OpenAPIValidationOff {
+		router, err := loadOpenAPIRouter()
+		if err != nil {
+			log.Fatalf("failed to load openapi contract: %v", err)
+		}
+		m.OpenAPI = router
+	}
+
+	return m
 }
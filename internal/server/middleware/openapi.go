@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"interview"
+	"interview/internal/apierror"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+// OPENAPI_VALIDATION 的取值
+const (
+	OpenAPIValidationOff    = "off"
+	OpenAPIValidationLog    = "log"
+	OpenAPIValidationReject = "reject"
+)
+
+// loadOpenAPIRouter 解析内嵌的openapi.yml并建立路由表
+func loadOpenAPIRouter() (routers.Router, error) {
+	// 日志里只要错误原因，不要整段schema
+	openapi3.SchemaErrorDetailsDisabled = true
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(interview.OpenAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	// servers里是线上域名，去掉后按路径匹配任意host
+	doc.Servers = nil
+	return gorillamux.NewRouter(doc)
+}
+
+// OpenAPIValidator 按openapi.yml校验请求（以及可选的响应）。
+// 放在鉴权之后、handler之前，这样缺少凭证仍然先返回401
+func (m *MiddlewareSet) OpenAPIValidator() gin.HandlerFunc {
+	if m.OpenAPI == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	reject := m.Config.OpenAPIValidation == OpenAPIValidationReject
+	options := &openapi3filter.Options{
+		MultiError: true,
+		// 鉴权由AuthBearer/RequireRaterID负责
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		route, pathParams, err := m.OpenAPI.FindRoute(c.Request)
+		if err != nil {
+			// 契约里没有的路由（如/docs）不校验
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			log.Printf("openapi: request %s %s does not match contract: %v", c.Request.Method, c.Request.URL.Path, err)
+			if reject {
+				apierror.Abort(c, apierror.Validation(contractDetails("", err)...))
+				return
+			}
+		}
+
+		if !m.Config.OpenAPIValidateResponses {
+			c.Next()
+			return
+		}
+
+		// 缓冲响应，校验完再写出
+		orig := c.Writer
+		rec := &bodyRecorder{ResponseWriter: orig}
+		c.Writer = rec
+		c.Next()
+		c.Writer = orig
+
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 orig.Status(),
+			Header:                 orig.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options:                options,
+		})
+		if err != nil {
+			log.Printf("openapi: response %d for %s %s does not match contract: %v",
+				orig.Status(), c.Request.Method, c.Request.URL.Path, err)
+			if reject {
+				orig.Header().Del("Content-Type")
+				orig.Header().Del("Location")
+				apierror.Write(c, apierror.Internal("Response does not match API contract"))
+				return
+			}
+		}
+
+		orig.WriteHeaderNow()
+		_, _ = orig.Write(rec.body.Bytes())
+	}
+}
+
+// bodyRecorder 暂存响应体，状态码和header仍记在原writer上（在真正写出之前可以覆盖）
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// contractDetails 把kin-openapi的错误展开成字段错误；请求体字段用JSON Pointer，参数用 in.name
+func contractDetails(loc string, err error) []apierror.FieldError {
+	// 这里用类型断言逐层展开：errors.As会穿过RequestError直接拿到内层错误，丢掉参数位置
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var out []apierror.FieldError
+		for _, inner := range e {
+			out = append(out, contractDetails(loc, inner)...)
+		}
+		return out
+	case *openapi3filter.RequestError:
+		if e.Parameter != nil {
+			loc = e.Parameter.In + "." + e.Parameter.Name
+		}
+		if e.Err == nil {
+			return []apierror.FieldError{{Field: loc, Message: e.Reason}}
+		}
+		return contractDetails(loc, e.Err)
+	case *openapi3.SchemaError:
+		field := loc
+		if ptr := e.JSONPointer(); len(ptr) > 0 {
+			field = loc + "/" + strings.Join(ptr, "/")
+		}
+		return []apierror.FieldError{{Field: field, Message: e.Reason}}
+	}
+
+	var parseErr *openapi3filter.ParseError
+	if errors.As(err, &parseErr) {
+		return []apierror.FieldError{{Field: loc, Message: parseErr.Error()}}
+	}
+	return []apierror.FieldError{{Field: loc, Message: err.Error()}}
+}
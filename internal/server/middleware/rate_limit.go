@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"interview/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 空闲超过这个时间的桶会被清理
+const bucketIdleTTL = 10 * time.Minute
+
+// RateLimitPolicy 令牌桶参数：每秒补充Rate个令牌，最多累积Burst个
+type RateLimitPolicy struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter 按key（rater/IP）维护的令牌桶
+type rateLimiter struct {
+	policy RateLimitPolicy
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(p RateLimitPolicy) *rateLimiter {
+	return &rateLimiter{
+		policy:    p,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// take 对所有key各取一个令牌；任一key不足时都不扣，返回需要等待的时间
+func (l *rateLimiter) take(now time.Time, keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, k := range keys {
+		b := l.refill(now, k)
+		if b.tokens < 1 {
+			w := time.Duration((1 - b.tokens) / l.policy.Rate * float64(time.Second))
+			if w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, k := range keys {
+		l.buckets[k].tokens--
+	}
+	return true, 0
+}
+
+func (l *rateLimiter) refill(now time.Time, key string) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.policy.Burst), lastSeen: now}
+		l.buckets[key] = b
+		return b
+	}
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(float64(l.policy.Burst), b.tokens+elapsed*l.policy.Rate)
+	b.lastSeen = now
+	return b
+}
+
+// sweep 定期清理空闲的桶，防止map无限增长
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	for k, b := range l.buckets {
+		if now.Sub(b.lastSeen) > bucketIdleTTL {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RateLimit 令牌桶限流，按客户端IP计数。放在鉴权之前，token或签名不对的请求也会被限流
+func (m *MiddlewareSet) RateLimit(p RateLimitPolicy) gin.HandlerFunc {
+	return m.rateLimit(p, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// RaterRateLimit 按RequireRaterID校验过后放进context的rater计数，不直接读X-Rater-Id，所以要放在RequireRaterID之后
+func (m *MiddlewareSet) RaterRateLimit(p RateLimitPolicy) gin.HandlerFunc {
+	return m.rateLimit(p, func(c *gin.Context) string {
+		if raterID := c.GetString("rater_id"); raterID != "" {
+			return "rater:" + raterID
+		}
+		return ""
+	})
+}
+
+// rateLimit key返回空时不限流
+func (m *MiddlewareSet) rateLimit(p RateLimitPolicy, key func(c *gin.Context) string) gin.HandlerFunc {
+	if !m.Config.RateLimitEnabled || p.Rate <= 0 || p.Burst <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limiter := newRateLimiter(p)
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		ok, wait := limiter.take(time.Now(), k)
+		if !ok {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			apierror.Abort(c, apierror.ErrTooManyRequests)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// 各路由组的限流策略
+func (m *MiddlewareSet) ReadRateLimit() gin.HandlerFunc {
+	return m.RateLimit(RateLimitPolicy{Rate: m.Config.RateLimitReadRPS, Burst: m.Config.RateLimitReadBurst})
+}
+
+func (m *MiddlewareSet) WriteRateLimit() gin.HandlerFunc {
+	return m.RateLimit(RateLimitPolicy{Rate: m.Config.RateLimitWriteRPS, Burst: m.Config.RateLimitWriteBurst})
+}
+
+// RatingRateLimit 评分提交按IP限流，放在RequireRaterID之前
+func (m *MiddlewareSet) RatingRateLimit() gin.HandlerFunc {
+	return m.RateLimit(RateLimitPolicy{Rate: m.Config.RateLimitRatingRPS, Burst: m.Config.RateLimitRatingBurst})
+}
+
+// RaterRatingRateLimit 评分提交按rater限流，放在RequireRaterID之后
+func (m *MiddlewareSet) RaterRatingRateLimit() gin.HandlerFunc {
+	return m.RaterRateLimit(RateLimitPolicy{Rate: m.Config.RateLimitRatingRPS, Burst: m.Config.RateLimitRatingBurst})
+}
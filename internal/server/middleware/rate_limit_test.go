@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"interview/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	type step struct {
+		after    time.Duration // 相对start
+		keys     []string
+		ok       bool
+		wantWait time.Duration
+	}
+	tests := []struct {
+		name   string
+		policy RateLimitPolicy
+		steps  []step
+	}{
+		{
+			name:   "burst then refill",
+			policy: RateLimitPolicy{Rate: 1, Burst: 2},
+			steps: []step{
+				{0, []string{"ip:a"}, true, 0},
+				{0, []string{"ip:a"}, true, 0},
+				{0, []string{"ip:a"}, false, time.Second},
+				{500 * time.Millisecond, []string{"ip:a"}, false, 500 * time.Millisecond},
+				{time.Second, []string{"ip:a"}, true, 0},
+			},
+		},
+		{
+			name:   "refill is capped at burst",
+			policy: RateLimitPolicy{Rate: 10, Burst: 1},
+			steps: []step{
+				{time.Hour, []string{"ip:a"}, true, 0},
+				{time.Hour, []string{"ip:a"}, false, 100 * time.Millisecond},
+			},
+		},
+		{
+			name:   "keys are independent",
+			policy: RateLimitPolicy{Rate: 1, Burst: 1},
+			steps: []step{
+				{0, []string{"ip:a"}, true, 0},
+				{0, []string{"ip:b"}, true, 0},
+				{0, []string{"ip:a"}, false, time.Second},
+			},
+		},
+		{
+			// 换IP也绕不过同一个rater的桶；被拒绝时不扣任何一个桶
+			name:   "all keys must have a token",
+			policy: RateLimitPolicy{Rate: 1, Burst: 1},
+			steps: []step{
+				{0, []string{"ip:a", "rater:u1"}, true, 0},
+				{0, []string{"ip:b", "rater:u1"}, false, time.Second},
+				{0, []string{"ip:b", "rater:u2"}, true, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.policy)
+			for i, s := range tt.steps {
+				ok, wait := l.take(start.Add(s.after), s.keys...)
+				if ok != s.ok || wait != s.wantWait {
+					t.Errorf("step %d: take(%v) = %v, %v; want %v, %v", i, s.keys, ok, wait, s.ok, s.wantWait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	start := time.Now()
+	l := newRateLimiter(RateLimitPolicy{Rate: 1, Burst: 1})
+	l.take(start, "ip:a")
+	l.take(start.Add(bucketIdleTTL), "ip:b")
+	l.take(start.Add(bucketIdleTTL+time.Minute), "ip:b")
+
+	if _, ok := l.buckets["ip:a"]; ok {
+		t.Errorf("idle bucket ip:a was not swept")
+	}
+	if _, ok := l.buckets["ip:b"]; !ok {
+		t.Errorf("active bucket ip:b was swept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		proxies []string
+		// 按rater计数（RaterRateLimit），否则按IP
+		byRater bool
+		// 两个请求各自的RemoteAddr、X-Forwarded-For和rater
+		first, second [3]string
+		wantSecond    int
+	}{
+		{
+			name:       "same ip",
+			first:      [3]string{"192.0.2.1:1000", "", ""},
+			second:     [3]string{"192.0.2.1:1001", "", ""},
+			wantSecond: http.StatusTooManyRequests,
+		},
+		{
+			name:       "spoofed forwarded for is ignored without trusted proxies",
+			first:      [3]string{"192.0.2.1:1000", "198.51.100.1", ""},
+			second:     [3]string{"192.0.2.1:1000", "198.51.100.2", ""},
+			wantSecond: http.StatusTooManyRequests,
+		},
+		{
+			name:       "forwarded for from a trusted proxy",
+			proxies:    []string{"192.0.2.0/24"},
+			first:      [3]string{"192.0.2.1:1000", "198.51.100.1", ""},
+			second:     [3]string{"192.0.2.1:1000", "198.51.100.2", ""},
+			wantSecond: http.StatusOK,
+		},
+		{
+			name:       "same rater from another ip",
+			byRater:    true,
+			first:      [3]string{"192.0.2.1:1000", "", "u1"},
+			second:     [3]string{"192.0.2.2:1000", "", "u1"},
+			wantSecond: http.StatusTooManyRequests,
+		},
+		{
+			name:       "other rater from the same ip",
+			byRater:    true,
+			first:      [3]string{"192.0.2.1:1000", "", "u1"},
+			second:     [3]string{"192.0.2.1:1000", "", "u2"},
+			wantSecond: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MiddlewareSet{Config: config.Config{RateLimitEnabled: true}}
+			policy := RateLimitPolicy{Rate: 0.001, Burst: 1}
+			limit := m.RateLimit(policy)
+			if tt.byRater {
+				limit = m.RaterRateLimit(policy)
+			}
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/", func(c *gin.Context) {
+				// 模拟RequireRaterID校验后写入的身份
+				if id := c.GetHeader("X-Test-Rater"); id != "" {
+					c.Set("rater_id", id)
+				}
+				c.Next()
+			}, limit, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			send := func(req [3]string) *httptest.ResponseRecorder {
+				hr := httptest.NewRequest(http.MethodGet, "/", nil)
+				hr.RemoteAddr = req[0]
+				if req[1] != "" {
+					hr.Header.Set("X-Forwarded-For", req[1])
+				}
+				if req[2] != "" {
+					hr.Header.Set("X-Test-Rater", req[2])
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, hr)
+				return w
+			}
+
+			if w := send(tt.first); w.Code != http.StatusOK {
+				t.Fatalf("first request status = %d, want 200", w.Code)
+			}
+			w := send(tt.second)
+			if w.Code != tt.wantSecond {
+				t.Fatalf("second request status = %d, want %d", w.Code, tt.wantSecond)
+			}
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Errorf("429 without Retry-After")
+			}
+		})
+	}
+}
@@ -1,22 +1,28 @@
 package middleware
 
 import (
-	"net/http"
+	"strings"
+	"time"
+
+	"interview/internal/apierror"
+	"interview/internal/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RequireRaterID 验证X-Rater-Id请求头
+// rater身份模式
+const (
+	RaterAuthHeader = "header" // 直接信任X-Rater-Id（仅开发环境）
+	RaterAuthHMAC   = "hmac"   // X-Rater-Token: 服务端密钥签名的token
+	RaterAuthJWT    = "jwt"    // Authorization: Bearer <jwt>，取JWT_RATER_CLAIM（默认sub）作为rater id
+)
+
+// RequireRaterID 验证rater身份，并把rater_id存进context
 func (m *MiddlewareSet) RequireRaterID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取X-Rater-Id头
-		raterID := c.GetHeader("X-Rater-Id")
-		if raterID == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    "UNAUTHORIZED",
-				"message": "Missing or invalid authentication information",
-			})
-			c.Abort()
+		raterID, ok := m.resolveRaterID(c)
+		if !ok || raterID == "" {
+			apierror.Abort(c, apierror.ErrUnauthorized)
 			return
 		}
 
@@ -25,3 +31,26 @@ func (m *MiddlewareSet) RequireRaterID() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func (m *MiddlewareSet) resolveRaterID(c *gin.Context) (string, bool) {
+	switch m.Config.RaterAuthMode {
+	case RaterAuthHMAC:
+		id, err := auth.VerifyRaterToken([]byte(m.Config.RaterTokenSecret), c.GetHeader("X-Rater-Token"), time.Now())
+		return id, err == nil
+
+	case RaterAuthJWT:
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return "", false
+		}
+		claims, err := m.RaterJWT.Verify(c.Request.Context(), parts[1], time.Now())
+		if err != nil {
+			return "", false
+		}
+		return m.jwtRaterID(claims), true
+
+	default:
+		// 获取X-Rater-Id头
+		return c.GetHeader("X-Rater-Id"), true
+	}
+}
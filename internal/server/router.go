@@ -1,12 +1,16 @@
 package server
 
 import (
-	//swaggerFiles "github.com/swaggo/files"
-	//ginSwagger "github.com/swaggo/gin-swagger"
+	"log"
+
+	"interview/internal/apierror"
+	"interview/internal/auth"
 	"interview/internal/server/handlers"
 	"interview/internal/server/middleware"
 
 	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type RouterDeps struct {
@@ -18,34 +22,110 @@ func NewRouter(deps RouterDeps) *gin.Engine {
 	// 使用 gin.New() 而不是 gin.Default()，这样可以避免默认的错误处理
 	r := gin.New()
 
+	// 只采信受信任代理转发来的X-Forwarded-For，默认不信任任何代理，避免客户端伪造IP绕过限流
+	if err := r.SetTrustedProxies(deps.Middleware.Config.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+
 	// 手动添加必要的中间件
 	r.Use(gin.Logger())
+	r.Use(deps.Middleware.RequestID())
 	// 全局错误处理
 	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		c.AbortWithStatusJSON(500, gin.H{"code": "INTERNAL_ERROR", "message": "Internal server error"})
+		apierror.Abort(c, apierror.ErrInternal)
 	}))
 
+	// 未匹配的路由也返回统一的错误结构
+	r.NoRoute(func(c *gin.Context) {
+		apierror.Write(c, apierror.NotFound("Resource"))
+	})
+
 	// 健康检查
 	r.GET("/healthz", deps.Handlers.Healthz)
 
+	// Prometheus指标
+	r.GET("/metrics", deps.Handlers.Metrics.Handler())
+
 	// 鉴权中间件
 	authRequired := deps.Middleware.AuthBearer()
 	raterIDRequired := deps.Middleware.RequireRaterID()
 
+	// 权限校验（放在authRequired之后），角色矩阵见 auth.RolePermissions，缺少权限返回403
+	//   editor: 创建/更新电影；moderator: 隐藏评分、查看刷分报告；admin: 删除、清理、token管理、审计日志
+	canWriteMovies := deps.Middleware.RequireScope(auth.ScopeMoviesWrite)
+	canDeleteMovies := deps.Middleware.RequireScope(auth.ScopeMoviesDelete)
+	canModerate := deps.Middleware.RequireScope(auth.ScopeReviewsModerate)
+	canReadReports := deps.Middleware.RequireScope(auth.ScopeReportsRead)
+	canPurge := deps.Middleware.RequireScope(auth.ScopeRatingsPurge)
+	canManageTokens := deps.Middleware.RequireScope(auth.ScopeTokensManage)
+	canReadAudit := deps.Middleware.RequireScope(auth.ScopeAuditRead)
+
+	// 限流中间件：评分提交最严格，其他写操作次之，读最宽松。
+	// 按IP的限流放在鉴权之前，伪造的token和rater签名也会被限流；raterLimit按校验过的rater计数，要放在raterIDRequired之后
+	readLimit := deps.Middleware.ReadRateLimit()
+	writeLimit := deps.Middleware.WriteRateLimit()
+	ratingLimit := deps.Middleware.RatingRateLimit()
+	raterLimit := deps.Middleware.RaterRatingRateLimit()
+
+	// OpenAPI契约校验（OPENAPI_VALIDATION），放在鉴权之后、handler之前
+	contract := deps.Middleware.OpenAPIValidator()
+
 	// 电影路由
 	// GET /movies - 列表和搜索（公开）
-	r.GET("/movies", deps.Handlers.ListMovies)
+	r.GET("/movies", readLimit, contract, deps.Handlers.ListMovies)
 
 	// POST /movies - 创建电影（需要鉴权）
-	r.POST("/movies", authRequired, deps.Handlers.CreateMovie)
+	r.POST("/movies", writeLimit, authRequired, canWriteMovies, contract, deps.Handlers.CreateMovie)
+
+	// PATCH /movies/:title - 更新电影（editor）
+	r.PATCH("/movies/:title", writeLimit, authRequired, canWriteMovies, contract, deps.Handlers.UpdateMovie)
+
+	// DELETE /movies/:title - 删除电影（admin）
+	r.DELETE("/movies/:title", writeLimit, authRequired, canDeleteMovies, contract, deps.Handlers.DeleteMovie)
+
+	// POST /movies/:title/boxoffice/refresh - 重新拉取票房数据（editor）
+	r.POST("/movies/:title/boxoffice/refresh", writeLimit, authRequired, canWriteMovies, contract, deps.Handlers.RefreshMovieBoxOffice)
+
+	// GET /movies/:title/boxoffice/history - 票房历史（公开）
+	r.GET("/movies/:title/boxoffice/history", readLimit, contract, deps.Handlers.BoxOfficeHistory)
 
 	// GET /movies/:title/rating - 获取评分聚合（公开）
-	r.GET("/movies/:title/rating", deps.Handlers.GetRating)
+	r.GET("/movies/:title/rating", readLimit, contract, deps.Handlers.GetRating)
 
 	// POST /movies/:title/ratings - 提交评分（需要X-Rater-Id）
-	r.POST("/movies/:title/ratings", raterIDRequired, deps.Handlers.SubmitRating)
-	// Swagger 文档
-	//r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.POST("/movies/:title/ratings", ratingLimit, raterIDRequired, raterLimit, contract, deps.Handlers.SubmitRating)
+
+	// GET /movies/:title/ratings - 评分列表，支持sort=helpful（公开）
+	r.GET("/movies/:title/ratings", readLimit, contract, deps.Handlers.ListReviews)
+
+	// POST /movies/:title/ratings/:raterId/votes - 有用性投票（需要X-Rater-Id）
+	r.POST("/movies/:title/ratings/:raterId/votes", ratingLimit, raterIDRequired, raterLimit, contract, deps.Handlers.SubmitVote)
+
+	// PUT /movies/:title/ratings/:raterId/hidden - 隐藏/恢复评分（moderator）
+	r.PUT("/movies/:title/ratings/:raterId/hidden", writeLimit, authRequired, canModerate, contract, deps.Handlers.SetRatingHidden)
+
+	// 管理接口（需要鉴权，具体权限见各路由）；限流放在鉴权之前
+	admin := r.Group("/admin")
+
+	// 刷分检测报告（moderator）和清理可疑评分（admin）
+	admin.GET("/rating-anomalies", readLimit, authRequired, canReadReports, contract, deps.Handlers.RatingAnomalies)
+	admin.DELETE("/rating-anomalies/:title", writeLimit, authRequired, canPurge, contract, deps.Handlers.PurgeSuspiciousRatings)
+
+	// 按条件批量刷新票房数据（editor）
+	admin.POST("/boxoffice/refresh", writeLimit, authRequired, canWriteMovies, contract, deps.Handlers.BulkRefreshBoxOffice)
+
+	// API token管理（admin）
+	admin.POST("/tokens", writeLimit, authRequired, canManageTokens, contract, deps.Handlers.CreateToken)
+	admin.GET("/tokens", readLimit, authRequired, canManageTokens, contract, deps.Handlers.ListTokens)
+	admin.DELETE("/tokens/:name", writeLimit, authRequired, canManageTokens, contract, deps.Handlers.RevokeToken)
+
+	// GET /admin/audit-log - 审计日志查询（admin）
+	admin.GET("/audit-log", readLimit, authRequired, canReadAudit, contract, deps.Handlers.ListAuditLog)
+
+	// API契约和Swagger UI（静态资源都内嵌在二进制里）
+	r.GET("/openapi.yml", deps.Handlers.OpenAPISpec)
+	r.GET("/docs", deps.Handlers.Docs)
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.yml")))
 
 	return r
 
@@ -0,0 +1,65 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"interview/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LatestJoin 把每部电影最新的一条票房快照关联成bo，要求movies的别名为m（没有快照时bo的字段都是NULL）
+const LatestJoin = `LEFT JOIN LATERAL (
+		SELECT s.revenue_worldwide, s.revenue_opening_weekend_usa, s.currency, s.source, s.last_updated
+		FROM boxoffice_snapshots s
+		WHERE s.movie_id = m.id
+		ORDER BY s.fetched_at DESC, s.id DESC
+		LIMIT 1
+	) bo ON TRUE`
+
+// Record 追加一条票房快照；box为nil（上游没有这部电影）时不写
+func Record(ctx context.Context, db sqlx.ExecerContext, movieID int, box *domain.BoxOffice, fetchedAt time.Time) error {
+	if box == nil {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO boxoffice_snapshots (movie_id, revenue_worldwide, revenue_opening_weekend_usa,
+		                                 currency, source, last_updated, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, movieID, box.Revenue.Worldwide, box.Revenue.OpeningWeekendUSA,
+		box.Currency, box.Source, box.LastUpdated, fetchedAt)
+	if err != nil {
+		return fmt.Errorf("record box office snapshot: %w", err)
+	}
+	return nil
+}
+
+// List 按拉取时间从旧到新返回快照，since/until为nil时不限制
+func List(ctx context.Context, db sqlx.QueryerContext, movieID int, since, until *time.Time, limit, offset int) ([]domain.BoxOfficeSnapshot, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT revenue_worldwide, revenue_opening_weekend_usa, currency, source, last_updated, fetched_at
+		FROM boxoffice_snapshots
+		WHERE movie_id = $1
+		  AND ($2::timestamp IS NULL OR fetched_at >= $2)
+		  AND ($3::timestamp IS NULL OR fetched_at < $3)
+		ORDER BY fetched_at, id
+		LIMIT $4 OFFSET $5
+	`, movieID, since, until, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list box office snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	items := []domain.BoxOfficeSnapshot{}
+	for rows.Next() {
+		var s domain.BoxOfficeSnapshot
+		if err := rows.Scan(&s.Revenue.Worldwide, &s.Revenue.OpeningWeekendUSA,
+			&s.Currency, &s.Source, &s.LastUpdated, &s.FetchedAt); err != nil {
+			return nil, fmt.Errorf("scan box office snapshot: %w", err)
+		}
+		items = append(items, s)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,86 @@
+package titles
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 只处理英文冠词，其他语言的冠词（如Die Hard的die）容易误伤
+var articles = map[string]bool{"the": true, "a": true, "an": true}
+
+var (
+	// 末尾的年份，如 "The Lion King (2019)"
+	yearSuffix = regexp.MustCompile(`^(.*\S)\s*\((\d{4})\)$`)
+	// 冠词放在末尾的写法，如 "Dark Knight, The"
+	trailingArticle = regexp.MustCompile(`(?i)^(.*\S)\s*,\s*(the|a|an)$`)
+)
+
+// Split 拆出标题末尾括号里的年份，没有时year为0
+func Split(title string) (base string, year int) {
+	title = strings.TrimSpace(title)
+	if m := yearSuffix.FindStringSubmatch(title); m != nil {
+		year, _ = strconv.Atoi(m[2])
+		return m[1], year
+	}
+	return title, 0
+}
+
+// reorder "Dark Knight, The" -> "The Dark Knight"
+func reorder(title string) string {
+	if m := trailingArticle.FindStringSubmatch(title); m != nil {
+		return m[2] + " " + m[1]
+	}
+	return title
+}
+
+// fold 去掉重音等附加符号（é -> e）并做兼容分解（全角字符、连字等）
+func fold(s string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return out
+}
+
+// Key 用来比较两个标题是否是同一部电影：忽略大小写、重音、标点、开头的冠词和末尾的(年份)，
+// "The Dark Knight"、"the dark knight"、"Dark Knight, The" 都是 "dark knight"
+func Key(title string) string {
+	base, _ := Split(title)
+	s := strings.ToLower(fold(reorder(base)))
+	s = strings.ReplaceAll(s, "&", " and ")
+	// 撇号直接去掉（Dead Man's -> dead mans），其他标点当作分隔
+	s = strings.NewReplacer("'", "", "’", "").Replace(s)
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// Alternate 上游按原标题没有查到合适结果时，第二次查询用的写法：冠词挪到前面、全角等兼容字符换成普通写法（NFKC，重音保留）；
+// 查到的是同名的另一部电影（mismatch，如年份对不上的翻拍）并且知道年份时再带上 "(年份)"。
+// 和原标题一样时返回空，不用再查。不会自己补上The之类的冠词
+func Alternate(title string, year int, mismatch bool) string {
+	title = strings.TrimSpace(title)
+	base, y := Split(title)
+	if year == 0 {
+		year = y
+	}
+	alt := norm.NFKC.String(reorder(base))
+	if mismatch && year > 0 {
+		alt += " (" + strconv.Itoa(year) + ")"
+	}
+	if alt == title {
+		return ""
+	}
+	return alt
+}
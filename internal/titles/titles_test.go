@@ -0,0 +1,92 @@
+package titles
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"The Dark Knight", "dark knight"},
+		{"the dark knight", "dark knight"},
+		{"Dark Knight, The", "dark knight"},
+		{"  THE DARK KNIGHT  ", "dark knight"},
+		{"The Lion King (2019)", "lion king"},
+		{"Amélie", "amelie"},
+		{"Léon: The Professional", "leon the professional"},
+		{"Pirates of the Caribbean: Dead Man's Chest", "pirates of the caribbean dead mans chest"},
+		{"Dead Man’s Chest", "dead mans chest"},
+		{"Fast & Furious", "fast and furious"},
+		{"Spider-Man: No Way Home", "spider man no way home"},
+		{"Ｔｈｅ Ｍａｔｒｉｘ", "matrix"},
+		{"A Beautiful Mind", "beautiful mind"},
+		{"Man, A", "man"},
+		// 只剩一个词时冠词保留
+		{"The", "the"},
+		{"It", "it"},
+		// 其他语言的冠词不处理
+		{"Die Hard", "die hard"},
+		{"2001: A Space Odyssey", "2001 a space odyssey"},
+		{"1917", "1917"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := Key(tt.title); got != tt.want {
+				t.Errorf("Key(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		title    string
+		wantBase string
+		wantYear int
+	}{
+		{"The Lion King (2019)", "The Lion King", 2019},
+		{"The Lion King(1994)", "The Lion King", 1994},
+		{"The Lion King", "The Lion King", 0},
+		{"1917", "1917", 0},
+		{"(2019)", "(2019)", 0},
+		{"Movie (19)", "Movie (19)", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			base, year := Split(tt.title)
+			if base != tt.wantBase || year != tt.wantYear {
+				t.Errorf("Split(%q) = %q, %d; want %q, %d", tt.title, base, year, tt.wantBase, tt.wantYear)
+			}
+		})
+	}
+}
+
+func TestAlternate(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		year     int
+		mismatch bool
+		want     string
+	}{
+		{"trailing article", "Dark Knight, The", 0, false, "The Dark Knight"},
+		{"full width", "Ｔｈｅ Ｍａｔｒｉｘ", 0, false, "The Matrix"},
+		{"accents kept", "Amélie", 0, false, ""},
+		{"nothing to change", "Inception", 0, false, ""},
+		{"no article added", "Dark Knight", 0, false, ""},
+		{"remake with year", "The Lion King", 2019, true, "The Lion King (2019)"},
+		{"year only on mismatch", "The Lion King", 2019, false, ""},
+		{"mismatch without year", "The Lion King", 0, true, ""},
+		{"year from title", "Lion King, The (2019)", 0, true, "The Lion King (2019)"},
+		{"year suffix dropped without mismatch", "Lion King, The (2019)", 0, false, "The Lion King"},
+		{"already has year", "The Lion King (2019)", 2019, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Alternate(tt.title, tt.year, tt.mismatch); got != tt.want {
+				t.Errorf("Alternate(%q, %d, %v) = %q, want %q", tt.title, tt.year, tt.mismatch, got, tt.want)
+			}
+		})
+	}
+}
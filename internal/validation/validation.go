@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"interview/internal/apierror"
+)
+
+const (
+	// DateLayout releaseDate的格式
+	DateLayout = "2006-01-02"
+
+	// MinReleaseYear 早于这一年的日期基本都是录入错误
+	MinReleaseYear = 1888
+
+	MaxTitleLength = 200
+	MaxBudget      = int64(10_000_000_000)
+)
+
+// MpaRatings 允许的MPA评级
+var MpaRatings = []string{"G", "PG", "PG-13", "R", "NC-17"}
+
+// Errors 收集字段错误，校验完所有字段后一次性返回
+type Errors struct {
+	details []apierror.FieldError
+}
+
+// Add 记录一个字段错误，field为JSON Pointer（如 /releaseDate）；每个字段只保留第一个错误
+func (e *Errors) Add(field, format string, args ...interface{}) {
+	e.Append(apierror.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Append 加入已有的字段错误（如apierror.BindLenient报的类型错误）
+func (e *Errors) Append(details ...apierror.FieldError) {
+	for _, d := range details {
+		if !e.has(d.Field) {
+			e.details = append(e.details, d)
+		}
+	}
+}
+
+func (e *Errors) has(field string) bool {
+	for _, d := range e.details {
+		if d.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err 没有错误时返回nil，否则返回带全部字段详情的BAD_REQUEST
+func (e *Errors) Err() error {
+	if len(e.details) == 0 {
+		return nil
+	}
+	return apierror.Validation(e.details...)
+}
+
+// Validator 电影字段校验，genre词表来自配置
+type Validator struct {
+	genres map[string]string // 小写 -> 词表中的写法
+	list   string
+}
+
+func New(genres []string) *Validator {
+	v := &Validator{genres: make(map[string]string, len(genres))}
+	for _, g := range genres {
+		v.genres[strings.ToLower(g)] = g
+	}
+	v.list = strings.Join(genres, ", ")
+	return v
+}
+
+// Title 非空，去掉首尾空白后不超过MaxTitleLength个字符
+func (v *Validator) Title(errs *Errors, field, title string) {
+	title = strings.TrimSpace(title)
+	switch {
+	case title == "":
+		errs.Add(field, "is required")
+	case utf8.RuneCountInString(title) > MaxTitleLength:
+		errs.Add(field, "must be at most %d characters", MaxTitleLength)
+	}
+}
+
+// Genre 必须在词表中（大小写不敏感），返回规范写法
+func (v *Validator) Genre(errs *Errors, field, genre string) string {
+	genre = strings.TrimSpace(genre)
+	if genre == "" {
+		errs.Add(field, "is required")
+		return genre
+	}
+	canonical, ok := v.genres[strings.ToLower(genre)]
+	if !ok {
+		errs.Add(field, "must be one of: %s", v.list)
+		return genre
+	}
+	return canonical
+}
+
+// ReleaseDate 必须是合法的YYYY-MM-DD日期，且不早于MinReleaseYear
+func (v *Validator) ReleaseDate(errs *Errors, field, date string) {
+	if date == "" {
+		errs.Add(field, "is required")
+		return
+	}
+	t, err := time.Parse(DateLayout, date)
+	if err != nil {
+		errs.Add(field, "must be a valid date in YYYY-MM-DD format")
+		return
+	}
+	if t.Year() < MinReleaseYear {
+		errs.Add(field, "must not be earlier than %d", MinReleaseYear)
+	}
+}
+
+// Budget 可选，提供时必须在 [0, MaxBudget] 之间
+func (v *Validator) Budget(errs *Errors, field string, budget *int64) {
+	if budget != nil && (*budget < 0 || *budget > MaxBudget) {
+		errs.Add(field, "must be between 0 and %d", MaxBudget)
+	}
+}
+
+// MpaRating 可选，提供时必须是MpaRatings之一
+func (v *Validator) MpaRating(errs *Errors, field string, rating *string) {
+	if rating == nil {
+		return
+	}
+	for _, r := range MpaRatings {
+		if *rating == r {
+			return
+		}
+	}
+	errs.Add(field, "must be one of: %s", strings.Join(MpaRatings, ", "))
+}
@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"interview/internal/apierror"
+)
+
+func TestValidator(t *testing.T) {
+	v := New([]string{"Sci-Fi", "Drama"})
+	budget, negative, tooLarge := int64(160000000), int64(-1), MaxBudget+1
+	pg13, pg13Lower := "PG-13", "pg-13"
+
+	tests := []struct {
+		name  string
+		check func(errs *Errors)
+		want  string // 空串表示没有错误
+	}{
+		{"title", func(e *Errors) { v.Title(e, "/title", "Inception") }, ""},
+		{"blank title", func(e *Errors) { v.Title(e, "/title", "  ") }, "is required"},
+		{"long title", func(e *Errors) { v.Title(e, "/title", strings.Repeat("é", MaxTitleLength+1)) }, "must be at most 200 characters"},
+		{"title at the limit", func(e *Errors) { v.Title(e, "/title", strings.Repeat("é", MaxTitleLength)) }, ""},
+		{"genre", func(e *Errors) { v.Genre(e, "/genre", "drama") }, ""},
+		{"missing genre", func(e *Errors) { v.Genre(e, "/genre", "") }, "is required"},
+		{"unknown genre", func(e *Errors) { v.Genre(e, "/genre", "Western") }, "must be one of: Sci-Fi, Drama"},
+		{"release date", func(e *Errors) { v.ReleaseDate(e, "/releaseDate", "2010-07-16") }, ""},
+		{"missing release date", func(e *Errors) { v.ReleaseDate(e, "/releaseDate", "") }, "is required"},
+		{"invalid release date", func(e *Errors) { v.ReleaseDate(e, "/releaseDate", "2010-02-30") }, "must be a valid date in YYYY-MM-DD format"},
+		{"release date too early", func(e *Errors) { v.ReleaseDate(e, "/releaseDate", "1887-12-31") }, "must not be earlier than 1888"},
+		{"budget", func(e *Errors) { v.Budget(e, "/budget", &budget) }, ""},
+		{"no budget", func(e *Errors) { v.Budget(e, "/budget", nil) }, ""},
+		{"negative budget", func(e *Errors) { v.Budget(e, "/budget", &negative) }, "must be between 0 and 10000000000"},
+		{"budget too large", func(e *Errors) { v.Budget(e, "/budget", &tooLarge) }, "must be between 0 and 10000000000"},
+		{"mpa rating", func(e *Errors) { v.MpaRating(e, "/mpaRating", &pg13) }, ""},
+		{"no mpa rating", func(e *Errors) { v.MpaRating(e, "/mpaRating", nil) }, ""},
+		{"mpa rating is case sensitive", func(e *Errors) { v.MpaRating(e, "/mpaRating", &pg13Lower) }, "must be one of: G, PG, PG-13, R, NC-17"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs Errors
+			tt.check(&errs)
+
+			var got string
+			if len(errs.details) > 0 {
+				got = errs.details[0].Message
+			}
+			if got != tt.want {
+				t.Errorf("message = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenreCanonical(t *testing.T) {
+	v := New([]string{"Sci-Fi"})
+	var errs Errors
+	if got := v.Genre(&errs, "/genre", " sci-fi "); got != "Sci-Fi" {
+		t.Errorf("Genre() = %q, want %q", got, "Sci-Fi")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	if err := errs.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil", err)
+	}
+
+	// 每个字段只保留第一个错误，字段按出现顺序返回
+	errs.Append(apierror.FieldError{Field: "/budget", Message: "must be of type int64"})
+	errs.Add("/title", "is required")
+	errs.Add("/budget", "must be between 0 and %d", MaxBudget)
+
+	var apiErr *apierror.Error
+	if !errors.As(errs.Err(), &apiErr) {
+		t.Fatalf("Err() = %v, want *apierror.Error", errs.Err())
+	}
+	if apiErr.Code != apierror.CodeBadRequest {
+		t.Errorf("Code = %s, want %s", apiErr.Code, apierror.CodeBadRequest)
+	}
+	got, _ := json.Marshal(apiErr.Details)
+	want := `[{"field":"/budget","message":"must be of type int64"},{"field":"/title","message":"is required"}]`
+	if string(got) != want {
+		t.Errorf("Details = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,8 @@
+package interview
+
+import _ "embed"
+
+// MockBoxOffice mock-boxoffice.json的内容，cmd/boxoffice-mock和测试里的假上游默认用它
+//
+//go:embed mock-boxoffice.json
+var MockBoxOffice []byte
@@ -0,0 +1,9 @@
+// Package interview 把仓库根目录下的API契约、票房mock数据和汇率表打包进二进制
+package interview
+
+import _ "embed"
+
+// OpenAPISpec openapi.yml的内容，/openapi.yml、文档页面和契约校验中间件都用它
+//
+//go:embed openapi.yml
+var OpenAPISpec []byte